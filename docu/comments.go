@@ -2,11 +2,14 @@ package docu
 
 import (
 	"go/ast"
+	"go/doc/comment"
 	"go/token"
 	"strings"
+	"unicode/utf8"
 )
 
-// TranslationProgress 返回 file 的翻译完成度.
+// TranslationProgress 返回 file 按注释分组计算的翻译完成度, 值为 0-100.
+// 与原文不同的注释分组即视作已翻译, 参见 ParagraphProgress.
 // 参数 file 应该是单文件的 Godocu 风格翻译文档.
 func TranslationProgress(file *ast.File) int {
 	var origin, trans int
@@ -21,7 +24,12 @@ func TranslationProgress(file *ast.File) int {
 		}
 		origin++
 		pos, src := docPosAndOrigin(comments, doc)
-		if src != nil && !EqualComment(doc, src) {
+		if text := doc.Text(); HasLangs(text) {
+			// 多语言文档以 Langs 选中的首个译文计
+			if o, t := SplitComments(text); t != "" && normalize(o) != normalize(t) {
+				trans++
+			}
+		} else if src != nil && !EqualComment(doc, src) {
 			trans++
 		}
 		comments = comments[pos+1:]
@@ -73,6 +81,63 @@ func TranslationProgress(file *ast.File) int {
 	return trans * 100 / origin
 }
 
+// ParagraphProgress 返回 file 按段落计算的翻译完成度, 值为 0-100.
+// 以 go/doc/comment 拆分文档为段落, 标题, 列表等文本块, 忽略代码块.
+// 原文文本块按长度加权, 在译文中原样出现的视作未翻译.
+// 参数 file 应该是单文件的 Godocu 风格翻译文档, 会调用 ClearComments.
+func ParagraphProgress(file *ast.File) int {
+	var total, trans int
+	ClearComments(file)
+	comments := file.Comments
+	if _, pos := License(file); pos != -1 {
+		comments = comments[pos+1:]
+	}
+
+	eachDoc(file, func(_ string, doc *ast.CommentGroup) {
+		origin, text := SplitComments(doc.Text())
+		if origin == "" {
+			if src := OriginDoc(comments, doc); src != nil {
+				origin = src.Text()
+			} else {
+				// 未翻译
+				origin = text
+			}
+		}
+		translated := make(map[string]bool)
+		for _, block := range textBlocks(text) {
+			translated[block] = true
+		}
+		for _, block := range textBlocks(origin) {
+			n := utf8.RuneCountInString(block)
+			total += n
+			if !translated[block] {
+				trans += n
+			}
+		}
+	})
+
+	if total == 0 {
+		return 100
+	}
+	return trans * 100 / total
+}
+
+// textBlocks 返回文档 text 中除代码块外各文本块合并空白后的文本.
+func textBlocks(text string) (blocks []string) {
+	var parser comment.Parser
+	var printer comment.Printer
+	for _, block := range parser.Parse(text).Content {
+		if _, ok := block.(*comment.Code); ok {
+			continue
+		}
+		s := normalize(string(printer.Text(&comment.Doc{Content: []comment.Block{block}})))
+		if s != "" {
+			blocks = append(blocks, s)
+		}
+	}
+	return
+}
+
 // License 返回 file 中以 copyright 开头的注释,和该注释的偏移量, 如果有的话.
 func License(file *ast.File) (lic string, pos int) {
 	end := file.Name.Pos()
@@ -103,6 +168,9 @@ func clearFile(file *ast.File) {
 	}
 	comments := file.Comments
 	for i := 0; i < len(comments); i++ {
+		if comments[i] == nil {
+			continue
+		}
 		if comments[i].Pos() >= end {
 			break
 		}
@@ -129,7 +197,8 @@ func docPosAndOrigin(comments []*ast.CommentGroup, trans *ast.CommentGroup) (int
 		return -1, nil
 	}
 	pos := indexOf(comments, trans.Pos())
-	if pos > 0 && isOrigin(comments[pos-1], trans.Pos()) {
+	// 相邻的注释不是原文, 参见 Notes
+	if pos > 0 && isOrigin(comments[pos-1], trans.Pos()) && NoteIdent(trans) == "" {
 		return pos, comments[pos-1]
 	}
 	return pos, nil
@@ -84,3 +84,30 @@ func TestOriginDoc(t *testing.T) {
 		t.Fatalf("WANT:\n%s\nDIFF:\n%s", want, got)
 	}
 }
+
+func TestParagraphProgress(t *testing.T) {
+	const src = `// First paragraph aaaa.
+//
+// Second paragraph bbbb.
+//
+//	code()
+
+// 第一段.
+//
+// Second paragraph bbbb.
+//
+//	code()
+package p
+
+// F does f.
+func F()
+`
+	file := parseGodocu(t, src)
+	if n := TranslationProgress(file); n != 50 {
+		t.Errorf("TranslationProgress = %d, want 50", n)
+	}
+	// 21 of 21 + 22 + 9 runes
+	if n := ParagraphProgress(file); n != 40 {
+		t.Errorf("ParagraphProgress = %d, want 40", n)
+	}
+}
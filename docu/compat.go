@@ -0,0 +1,199 @@
+package docu
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// API 兼容性分类, 参见 DiffRecord.Compat.
+// 以 target 为旧版本, source 为新版本进行判定.
+const (
+	Breaking   = "breaking"   // 不兼容的变更, 比如删除声明, 修改类型
+	Compatible = "compatible" // 兼容的添加, 比如新增声明, 新增结构体字段
+	Cosmetic   = "cosmetic"   // 不影响 API, 比如参数或结果改名, 文档变更
+)
+
+// specCompat 返回旧版本 old 到新版本 spec 的签名变更的兼容性分类.
+func specCompat(spec, old ast.Spec) string {
+	switch n := spec.(type) {
+	case *ast.ValueSpec:
+		o, ok := old.(*ast.ValueSpec)
+		if ok && exprKey(n.Type) == exprKey(o.Type) {
+			return Cosmetic
+		}
+	case *ast.TypeSpec:
+		o, ok := old.(*ast.TypeSpec)
+		if !ok || n.Assign.IsValid() != o.Assign.IsValid() ||
+			fieldsKey(n.TypeParams) != fieldsKey(o.TypeParams) {
+			break
+		}
+		return typeCompat(n.Type, o.Type)
+	}
+	return Breaking
+}
+
+// typeCompat 返回类型 old 变更为 typ 的兼容性分类.
+// 结构体只新增导出字段为兼容, 只变更非导出字段为无影响.
+func typeCompat(typ, old ast.Expr) string {
+	if exprKey(typ) == exprKey(old) {
+		return Cosmetic
+	}
+	st, ok := typ.(*ast.StructType)
+	ot, _ := old.(*ast.StructType)
+	if !ok || ot == nil {
+		return Breaking
+	}
+	fields, ofields := exportedFields(st.Fields), exportedFields(ot.Fields)
+	for name, key := range ofields {
+		if fields[name] != key {
+			return Breaking
+		}
+	}
+	if len(fields) != len(ofields) {
+		return Compatible
+	}
+	return Cosmetic
+}
+
+// exportedFields 返回 list 中导出字段名称到其类型的映射, 含嵌入字段.
+func exportedFields(list *ast.FieldList) map[string]string {
+	m := make(map[string]string)
+	if list == nil {
+		return m
+	}
+	for _, field := range list.List {
+		key := exprKey(field.Type)
+		if len(field.Names) == 0 {
+			if ident := recvTypeIdent(field.Type); ident != nil && ident.IsExported() {
+				m[ident.Name] = key
+			}
+			continue
+		}
+		for _, ident := range field.Names {
+			if ident.IsExported() {
+				m[ident.Name] = key
+			}
+		}
+	}
+	return m
+}
+
+// funcCompat 返回旧版本 old 到新版本 fn 的签名变更的兼容性分类.
+// 只是参数, 结果, 接收者或接收者的类型参数改名视作无影响.
+func funcCompat(fn, old *ast.FuncDecl) string {
+	if recvParamsKey(fn, fieldsKey(fn.Recv)) == recvParamsKey(old, fieldsKey(old.Recv)) &&
+		recvParamsKey(fn, exprKey(fn.Type)) == recvParamsKey(old, exprKey(old.Type)) {
+		return Cosmetic
+	}
+	return Breaking
+}
+
+// exprKey 返回去除函数参数和结果名称后 expr 的字面值, 用于比较类型.
+func exprKey(expr ast.Expr) string {
+	if expr == nil {
+		return ""
+	}
+	return types.ExprString(unnamed(expr))
+}
+
+// fieldsKey 返回去除名称后 list 的字面值.
+func fieldsKey(list *ast.FieldList) string {
+	if list == nil {
+		return ""
+	}
+	return FieldListLit(unnamedFields(list))
+}
+
+// unnamed 返回去除 expr 中函数参数和结果名称的拷贝.
+// 结构体字段和接口方法名称是 API 的一部分, 予以保留.
+func unnamed(expr ast.Expr) ast.Expr {
+	switch n := expr.(type) {
+	case *ast.FuncType:
+		return &ast.FuncType{
+			TypeParams: n.TypeParams,
+			Params:     unnamedFields(n.Params),
+			Results:    unnamedFields(n.Results),
+		}
+	case *ast.StarExpr:
+		return &ast.StarExpr{X: unnamed(n.X)}
+	case *ast.ParenExpr:
+		return unnamed(n.X)
+	case *ast.Ellipsis:
+		return &ast.Ellipsis{Elt: unnamed(n.Elt)}
+	case *ast.ArrayType:
+		return &ast.ArrayType{Len: n.Len, Elt: unnamed(n.Elt)}
+	case *ast.MapType:
+		return &ast.MapType{Key: unnamed(n.Key), Value: unnamed(n.Value)}
+	case *ast.ChanType:
+		return &ast.ChanType{Dir: n.Dir, Value: unnamed(n.Value)}
+	case *ast.StructType:
+		return &ast.StructType{Fields: typedFields(n.Fields)}
+	case *ast.InterfaceType:
+		return &ast.InterfaceType{Methods: typedFields(n.Methods)}
+	}
+	return expr
+}
+
+// unnamedFields 返回去除名称的 list 拷贝, 多个名称的字段展开为多个类型.
+func unnamedFields(list *ast.FieldList) *ast.FieldList {
+	if list == nil {
+		return nil
+	}
+	fields := &ast.FieldList{}
+	for _, field := range list.List {
+		typ := unnamed(field.Type)
+		for i := 0; i == 0 || i < len(field.Names); i++ {
+			fields.List = append(fields.List, &ast.Field{Type: typ})
+		}
+	}
+	return fields
+}
+
+// typedFields 返回保留名称, 类型去除函数参数名称的 list 拷贝.
+func typedFields(list *ast.FieldList) *ast.FieldList {
+	if list == nil {
+		return nil
+	}
+	fields := &ast.FieldList{}
+	for _, field := range list.List {
+		fields.List = append(fields.List, &ast.Field{
+			Names: field.Names,
+			Type:  unnamed(field.Type),
+			Tag:   field.Tag,
+		})
+	}
+	return fields
+}
+
+// DiffSummary 为单个包差异记录的兼容性统计.
+type DiffSummary struct {
+	Package                        string
+	Breaking, Compatible, Cosmetic int
+}
+
+// SummarizeDiffs 统计 records 的兼容性分类.
+func SummarizeDiffs(records []DiffRecord) (s DiffSummary) {
+	for _, r := range records {
+		s.Package = r.Package
+		switch r.Compat {
+		case Breaking:
+			s.Breaking++
+		case Compatible:
+			s.Compatible++
+		default:
+			s.Cosmetic++
+		}
+	}
+	return
+}
+
+// BreakingDiffs 返回 records 中不兼容的差异记录.
+func BreakingDiffs(records []DiffRecord) []DiffRecord {
+	var breaking []DiffRecord
+	for _, r := range records {
+		if r.Compat == Breaking {
+			breaking = append(breaking, r)
+		}
+	}
+	return breaking
+}
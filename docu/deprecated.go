@@ -0,0 +1,121 @@
+package docu
+
+import (
+	"go/ast"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// OmitDeprecated 表示 code, tmpl 输出时剔除已废弃的声明, 参见 DeprecatedFileFilter.
+var OmitDeprecated bool
+
+// IsDeprecated 返回文档 text 是否含有以 "Deprecated:" 开头的段落, 即 Go 惯例的已废弃.
+// "Deprecated:" 之后应为空白或段落结尾. 双语文档的原文和译文都被检查,
+// 译文可以保留 "Deprecated:".
+func IsDeprecated(text string) bool {
+	for _, p := range paragraphs(text) {
+		rest := strings.TrimPrefix(p, "Deprecated:")
+		if rest == p {
+			continue
+		}
+		if r, _ := utf8.DecodeRuneInString(rest); rest == "" || unicode.IsSpace(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// docDeprecated 返回文档 doc 是否已废弃, comments 非 nil 时同时检查 OriginDoc.
+func docDeprecated(doc *ast.CommentGroup, comments []*ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	return IsDeprecated(doc.Text()) ||
+		comments != nil && IsDeprecated(OriginDoc(comments, doc).Text())
+}
+
+// IsDeprecatedDecl 返回 decl 是否已废弃.
+// 只有一个 spec 的 const, var, type 声明也检查 spec 的文档.
+func IsDeprecatedDecl(decl ast.Decl) bool {
+	return declDeprecated(decl, nil)
+}
+
+func declDeprecated(decl ast.Decl, comments []*ast.CommentGroup) bool {
+	switch n := decl.(type) {
+	case *ast.FuncDecl:
+		return docDeprecated(n.Doc, comments)
+	case *ast.GenDecl:
+		if docDeprecated(n.Doc, comments) {
+			return true
+		}
+		if len(n.Specs) == 1 {
+			return docDeprecated(SpecDoc(n.Specs[0]), comments)
+		}
+	}
+	return false
+}
+
+// DeprecatedFileFilter 剔除 non-nil file 中已废弃的声明, 返回该 file 是否还具有声明.
+// 分组声明中已废弃的 spec 以及已废弃类型的方法也被剔除.
+// Godocu 风格文档会调用 ClearComments, 以便检查原文.
+func DeprecatedFileFilter(file *ast.File) bool {
+	var comments []*ast.CommentGroup
+	if IsGodocuFile(file) {
+		ClearComments(file)
+		comments = file.Comments
+	}
+	types := make(map[string]bool) // 已剔除的类型
+	decls := file.Decls[:0]
+	for _, node := range file.Decls {
+		switch decl := node.(type) {
+		case *ast.FuncDecl:
+			if docDeprecated(decl.Doc, comments) {
+				continue
+			}
+		case *ast.GenDecl:
+			deprecated := docDeprecated(decl.Doc, comments)
+			specs := decl.Specs[:0]
+			for _, spec := range decl.Specs {
+				if deprecated || docDeprecated(SpecDoc(spec), comments) {
+					if ts, ok := spec.(*ast.TypeSpec); ok {
+						types[ts.Name.Name] = true
+					}
+					continue
+				}
+				specs = append(specs, spec)
+			}
+			decl.Specs = specs
+			if len(specs) == 0 {
+				continue
+			}
+		}
+		decls = append(decls, node)
+	}
+
+	file.Decls = decls[:0]
+	for _, node := range decls {
+		if fn, ok := node.(*ast.FuncDecl); ok && fn.Recv != nil {
+			if lit := RecvIdentLit(fn); types[strings.TrimPrefix(lit, "*")] {
+				continue
+			}
+		}
+		file.Decls = append(file.Decls, node)
+	}
+	return len(file.Decls) != 0
+}
+
+// DeprecatedCount 返回文档 file 中已废弃的声明, 字段和接口方法的个数, 不含包文档.
+func DeprecatedCount(file *ast.File) (n int) {
+	var comments []*ast.CommentGroup
+	if IsGodocuFile(file) {
+		ClearComments(file)
+		comments = file.Comments
+	}
+	eachDoc(file, func(ident string, doc *ast.CommentGroup) {
+		if ident != "package" && NoteIdent(doc) == "" && docDeprecated(doc, comments) {
+			n++
+		}
+	})
+	return
+}
@@ -0,0 +1,148 @@
+package docu
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestIsDeprecated(t *testing.T) {
+	for _, tt := range []struct {
+		text string
+		want bool
+	}{
+		{"Deprecated: use B.\n", true},
+		{"A is a.\n\nDeprecated: use B.\n", true},
+		{"A is a.\nDeprecated: use B.\n", false},
+		{"A is not Deprecated: really.\n", false},
+		{"A is a.\n\nDeprecated:\nuse B.\n", true},
+		{"A is a.\n\nDeprecated:\n", true},
+		{"Deprecated:use B.\n", false},
+		{"A is a.\n\nDeprecated:\u3000请使用 B.\n", true},
+		{"Deprecated:\u00a0use B.\n", true},
+		{"Deprecated:\u0105 use B.\n", false},
+		{"A is a.\n\n" + GoDocu_Dividing_line + "\n\nDeprecated: 请使用 B.\n", true},
+	} {
+		if got := IsDeprecated(tt.text); got != tt.want {
+			t.Errorf("IsDeprecated(%q) = %v", tt.text, got)
+		}
+	}
+}
+
+const deprecatedSrc = `package p
+
+// A is a.
+//
+// Deprecated: use B.
+const A = 1
+
+const (
+	// B is b.
+	B = 2
+	// C is c.
+	//
+	// Deprecated: use B.
+	C = 3
+)
+
+// T is t.
+//
+// Deprecated: use U.
+type T struct{}
+
+// M does m.
+func (t *T) M() {}
+
+// U is u.
+type U struct {
+	// X is x.
+	//
+	// Deprecated: unused.
+	X int
+}
+
+// F does f.
+//
+// Deprecated: use G.
+func F() {}
+
+// G does g.
+func G() {}
+`
+
+func TestDeprecatedFileFilter(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "p.go", deprecatedSrc, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := DeprecatedCount(file); n != 5 {
+		t.Errorf("DeprecatedCount = %d, want 5", n)
+	}
+	if !DeprecatedFileFilter(file) {
+		t.Fatal("DeprecatedFileFilter removed all decls")
+	}
+	var buf bytes.Buffer
+	if err = Fprint(&buf, file); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, s := range []string{"const A", "C = 3", "type T", "func (t *T) M", "func F"} {
+		if strings.Contains(out, s) {
+			t.Errorf("DeprecatedFileFilter left %q:\n%s", s, out)
+		}
+	}
+	for _, s := range []string{"B = 2", "type U", "func G"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("DeprecatedFileFilter removed %q:\n%s", s, out)
+		}
+	}
+}
+
+func TestDiffsDeprecated(t *testing.T) {
+	const dst = `package p
+
+// A is a.
+const A = 1
+
+// F does f.
+//
+// Deprecated: use G.
+func F() {}
+`
+	const src = `package p
+
+// A is a.
+//
+// Deprecated: use B.
+const A = 1
+
+// F does f.
+func F() {}
+`
+	fset := token.NewFileSet()
+	source, err := parser.ParseFile(fset, "src.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := parser.ParseFile(fset, "dst.go", dst, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Index(source)
+	Index(target)
+
+	got := Diffs(source, target, false)
+	if len(got) != 2 || got[0].Ident != "A" || got[0].Change != DiffDeprecated ||
+		got[1].Ident != "F" || got[1].Change != DiffUndeprecated || got[1].Compat != Cosmetic {
+		t.Fatalf("Diffs = %+v", got)
+	}
+	var buf bytes.Buffer
+	if err = FprintDiffs(&buf, got); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "Const A deprecated:") {
+		t.Errorf("FprintDiffs =\n%s", out)
+	}
+}
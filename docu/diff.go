@@ -3,7 +3,6 @@ package docu
 import (
 	"go/ast"
 	"io"
-	"strconv"
 	"strings"
 )
 
@@ -52,241 +51,294 @@ func lineString(str string) string {
 	return str
 }
 
-// FirstDiff 对比输出两个已排序 ast.File 首个差异, 返回是否有差异及发生的错误.
-func FirstDiff(w io.Writer, source, target *ast.File) (diff bool, err error) {
-	const nl = "\n\n"
-	diff, err = TextDiff(w, "package "+source.Name.String(), "package "+target.Name.String())
-	if diff || err != nil {
-		return
-	}
+// 差异类别, 参见 DiffRecord.Change.
+const (
+	DiffAdded     = "added"     // 仅 source 具有, 即新版本新增
+	DiffRemoved   = "removed"   // 仅 target 具有, 即新版本删除
+	DiffSignature = "signature" // 签名, 类型或包名不同
+	DiffDoc       = "doc"       // 文档不同
+	DiffForm      = "form"      // 文档只是排版不同
 
-	diff, err = TextDiff(w, source.Doc.Text(), target.Doc.Text())
-	if diff || err != nil {
-		return
-	}
+	DiffDeprecated   = "deprecated"   // 仅 source 中已废弃, 即新版本废弃, 参见 IsDeprecated
+	DiffUndeprecated = "undeprecated" // 仅 target 中已废弃, 即新版本不再废弃
+)
 
-	diff, err = TextDiff(w, ImportsString(source.Imports), ImportsString(target.Imports))
-	if diff || err != nil {
-		return
-	}
+// diffKinds 为 DiffRecord.Kind 的取值, 下标为 NodeNumber.
+var diffKinds = []string{
+	ImportNum: "import",
+	ConstNum:  "const",
+	VarNum:    "var",
+	TypeNum:   "type",
+	FuncNum:   "func",
+	MethodNum: "method",
+}
 
-	return firstDecls(w, source.Decls, target.Decls)
+// DiffRecord 表示 source, target 之间的一个差异.
+// 兼容性分类以 target 为旧版本, source 为新版本.
+type DiffRecord struct {
+	Package string // 包名, 调用者可替换为 import paths
+	Kind    string // 声明类别: package, import, const, var, type, func, method
+	Ident   string // 标识符, 方法形如 "*List.Front"
+	Change  string // 差异类别: added, removed, signature, doc, form, deprecated, undeprecated
+	Compat  string // 兼容性分类: breaking, compatible, cosmetic
+	Source  string // source 一侧的签名或文档, 没有时为空
+	Target  string // target 一侧的签名或文档, 没有时为空
 }
 
-func firstDecls(w io.Writer, source, target []ast.Decl) (diff bool, err error) {
-	sd, so := declsOf(ConstNum, source, 0)
-	dd, do := declsOf(ConstNum, target, 0)
-	diff, err = diffGenDecls(w, "Const ", sd, dd)
-	if diff || err != nil {
-		return
+// title 返回 r 在文本输出中的标题, 形如 "Func Rename".
+func (r DiffRecord) title() string {
+	title := strings.ToUpper(r.Kind[:1]) + r.Kind[1:]
+	if r.Ident != "" {
+		title += " " + r.Ident
 	}
+	return title
+}
 
-	sd, so = declsOf(VarNum, source, so)
-	dd, do = declsOf(VarNum, target, do)
-	diff, err = diffGenDecls(w, "Var ", sd, dd)
-	if diff || err != nil {
-		return
+// isDoc 返回 r 是否为文档差异, 包括废弃状态的变更.
+func (r DiffRecord) isDoc() bool {
+	switch r.Change {
+	case DiffDoc, DiffForm, DiffDeprecated, DiffUndeprecated:
+		return true
 	}
+	return false
+}
 
-	sd, so = declsOf(TypeNum, source, so)
-	dd, do = declsOf(TypeNum, target, do)
-	diff, err = diffGenDecls(w, "Type ", sd, dd)
-	if diff || err != nil {
-		return
+// text 返回 r 一侧的 s 在文本输出中的形式.
+func (r DiffRecord) text(s string) string {
+	if r.Kind == "package" || r.Kind == "import" {
+		return s
 	}
+	switch r.Change {
+	case DiffDoc, DiffForm:
+		return r.title() + " doc:\n\n" + s
+	case DiffDeprecated, DiffUndeprecated:
+		return r.title() + " " + r.Change + ":\n\n" + s
+	}
+	if s == "" || r.Kind == "func" || r.Kind == "method" {
+		return s
+	}
+	return strings.ToUpper(r.Kind[:1]) + r.Kind[1:] + " " + s
+}
 
-	sd, so = declsOf(FuncNum, source, so)
-	dd, do = declsOf(FuncNum, target, do)
-	diff, err = diffFuncDecls(w, "Func ", sd, dd)
-	if diff || err != nil {
-		return
+// 文档差异的输出方式, 参见 DiffPrinter.Doc.
+const (
+	DocBlock = "block" // 完整输出两侧文档
+	DocLine  = "line"  // 输出 unified diff 风格的行差异
+	DocWord  = "word"  // 输出单词差异, 支持 CJK
+)
+
+// DiffPrinter 以 TEXT:/DIFF: 文本块形式输出差异记录, 文档排版差异使用 FORM:.
+type DiffPrinter struct {
+	// Doc 为文档差异的输出方式, 空值等同 DocBlock.
+	// DocLine, DocWord 方式以 DOC:/FORM: 加标题开头, 后跟差异片段.
+	Doc string
+	// Context 为 DocLine, DocWord 方式中变化行前后保留的行数.
+	Context int
+}
+
+// Fprint 输出 records.
+func (p DiffPrinter) Fprint(w io.Writer, records []DiffRecord) (err error) {
+	const prefix = "    "
+	for _, r := range records {
+		if !r.isDoc() || p.Doc != DocLine && p.Doc != DocWord {
+			err = diffOut(r.Change == DiffForm, w, r.text(r.Source), r.text(r.Target))
+		} else {
+			label := "DOC: "
+			switch r.Change {
+			case DiffForm:
+				label = "FORM: "
+			case DiffDeprecated, DiffUndeprecated:
+				label = strings.ToUpper(r.Change) + ": "
+			}
+			err = fprint(w, label, r.title(), "\n")
+			if err == nil && p.Doc == DocLine {
+				err = LineDiff(w, prefix, r.Source, r.Target, p.Context)
+			} else if err == nil {
+				err = WordDiff(w, prefix, r.Source, r.Target, p.Context)
+			}
+			if err == nil {
+				err = fprint(w, "\n")
+			}
+		}
+		if err != nil {
+			break
+		}
 	}
+	return
+}
 
-	sd, so = declsOf(MethodNum, source, so)
-	dd, do = declsOf(MethodNum, target, do)
-	return diffFuncDecls(w, "Method ", sd, dd)
+// FprintDiffs 以 DiffPrinter 的缺省方式输出 records.
+func FprintDiffs(w io.Writer, records []DiffRecord) error {
+	return DiffPrinter{}.Fprint(w, records)
+}
+
+// FirstDiff 对比输出两个已排序 ast.File 首个差异, 返回是否有差异及发生的错误.
+func FirstDiff(w io.Writer, source, target *ast.File) (diff bool, err error) {
+	records := Diffs(source, target, true)
+	return len(records) != 0, FprintDiffs(w, records)
 }
 
 // Diff 对比输出两个已排序 ast.File 差异, 返回是否有差异及发生的错误.
 // 如果包名称不同, 停止继续对比.
 func Diff(w io.Writer, source, target *ast.File) (diff bool, err error) {
-	const nl = "\n\n"
-	var out bool
-	diff, err = TextDiff(w, "package "+source.Name.String(), "package "+target.Name.String())
-	if diff || err != nil {
-		return
-	}
-	out, err = TextDiff(w, source.Doc.Text(), target.Doc.Text())
-	if diff = diff || out; err != nil {
-		return
+	records := Diffs(source, target, false)
+	return len(records) != 0, FprintDiffs(w, records)
+}
+
+// Diffs 返回两个已排序 ast.File 的差异记录, first 为 true 时只返回首个差异.
+// 如果包名称不同, 停止继续对比.
+func Diffs(source, target *ast.File, first bool) []DiffRecord {
+	sname, dname := source.Name.String(), target.Name.String()
+	d := &differ{pkg: sname, first: first}
+	if sname != dname {
+		d.add("package", sname, DiffSignature, Breaking, "package "+sname, "package "+dname)
+		return d.records
 	}
 
-	out, err = TextDiff(w, ImportsString(source.Imports), ImportsString(target.Imports))
-	if diff = diff || out; err != nil {
-		return
+	d.doc("package", sname, source.Doc.Text(), target.Doc.Text())
+
+	slit, dlit := ImportsString(source.Imports), ImportsString(target.Imports)
+	if slit != dlit {
+		d.add("import", "", DiffSignature, Cosmetic, slit, dlit)
 	}
 
-	out, err = diffDecls(w, source.Decls, target.Decls)
-	diff = diff || out
-	return
+	d.decls(source.Decls, target.Decls)
+	return d.records
 }
 
-// diffDecls 对比输出两个已排序 []ast.Decl 差异, 返回是否有差异及发生的错误.
-func diffDecls(w io.Writer, source, target []ast.Decl) (diff bool, err error) {
-	var out bool
-	sd, so := declsOf(ConstNum, source, 0)
-	dd, do := declsOf(ConstNum, target, 0)
-	out, err = diffGenDecls(w, "Const ", sd, dd)
-	if diff = diff || out; err != nil {
-		return
-	}
+// differ 收集差异记录, first 为 true 时收集到首个差异即停止.
+type differ struct {
+	pkg     string
+	first   bool
+	records []DiffRecord
+}
 
-	sd, so = declsOf(VarNum, source, so)
-	dd, do = declsOf(VarNum, target, do)
-	out, err = diffGenDecls(w, "Var ", sd, dd)
-	if diff = diff || out; err != nil {
-		return
-	}
+func (d *differ) done() bool {
+	return d.first && len(d.records) != 0
+}
 
-	sd, so = declsOf(TypeNum, source, so)
-	dd, do = declsOf(TypeNum, target, do)
-	out, err = diffGenDecls(w, "Type ", sd, dd)
-	if diff = diff || out; err != nil {
-		return
+func (d *differ) add(kind, ident, change, compat, source, target string) {
+	if !d.done() {
+		d.records = append(d.records, DiffRecord{d.pkg, kind, ident, change, compat, source, target})
 	}
+}
 
-	sd, so = declsOf(FuncNum, source, so)
-	dd, do = declsOf(FuncNum, target, do)
-	out, err = diffFuncDecls(w, "Func ", sd, dd)
-	if diff = diff || out; err != nil {
+// doc 对比文档 source, target, 区分排版差异和废弃状态的变更.
+// 同 DiffAdded, 以 target 为旧版本, source 为新版本判定废弃状态的变更.
+func (d *differ) doc(kind, ident, source, target string) {
+	if source == target {
 		return
 	}
+	if deprecated := IsDeprecated(source); deprecated != IsDeprecated(target) {
+		change := DiffDeprecated
+		if !deprecated {
+			change = DiffUndeprecated
+		}
+		d.add(kind, ident, change, Cosmetic, source, target)
+	} else if DiffFormOnly(source, target) {
+		d.add(kind, ident, DiffForm, Cosmetic, source, target)
+	} else {
+		d.add(kind, ident, DiffDoc, Cosmetic, source, target)
+	}
+}
 
-	sd, so = declsOf(MethodNum, source, so)
-	dd, do = declsOf(MethodNum, target, do)
-	out, err = diffFuncDecls(w, "Method ", sd, dd)
-	diff = diff || out
-	return
+// decls 对比两个已排序 []ast.Decl.
+func (d *differ) decls(source, target []ast.Decl) {
+	var sd, dd []ast.Decl
+	var so, do int
+	for num := ConstNum; num <= MethodNum && !d.done(); num++ {
+		sd, so = declsOf(num, source, so)
+		dd, do = declsOf(num, target, do)
+		if num < FuncNum {
+			d.genDecls(diffKinds[num], sd, dd)
+		} else {
+			d.funcDecls(diffKinds[num], sd, dd)
+		}
+	}
 }
 
 // 需要优化 SortDecl 搜索效率
 
-func diffGenDecls(w io.Writer, prefix string, source, target []ast.Decl) (diff bool, err error) {
-	ss := SortDecl(source)
-	dd := SortDecl(target)
-	if ss.Len() == 0 && dd.Len() == 0 {
-		return
-	}
-	if ss.Len() == 0 || dd.Len() == 0 {
-		return TextDiff(w, prefix+strconv.Itoa(ss.Len()), prefix+strconv.Itoa(dd.Len()))
+// typeLit 连接标识符 lit 和类型字面值 typ, 泛型类型参数紧随 lit.
+func typeLit(lit, typ string) string {
+	if typ == "" || strings.HasPrefix(typ, "[") && !strings.HasPrefix(typ, "[]") {
+		return lit + typ
 	}
-	var lit string
+	return lit + " " + typ
+}
 
+func (d *differ) genDecls(kind string, source, target []ast.Decl) {
+	ss := SortDecl(source)
+	dd := SortDecl(target)
 	for _, node := range ss {
 		decl := node.(*ast.GenDecl)
 		for _, spec := range decl.Specs {
-			lit = SpecIdentLit(spec)
-			if lit == "_" {
+			lit := SpecIdentLit(spec)
+			if lit == "_" || d.done() {
 				continue
 			}
-			targ, _, _ := dd.SearchSpec(lit)
+			slit := typeLit(lit, SpecTypeLit(spec))
+			targ, tdecl, _ := dd.SearchSpec(lit)
 			if targ == nil {
-				diff, err = true, diffOut(false, w, prefix+lit, "")
-				if err != nil {
-					return
-				}
+				d.add(kind, lit, DiffAdded, Compatible, slit, "")
 				continue
 			}
 			// 类型
-			slit, dlit := SpecTypeLit(spec), SpecTypeLit(targ)
-
+			dlit := typeLit(lit, SpecTypeLit(targ))
 			if slit != dlit {
-				diff, err = true, diffOut(false, w, prefix+lit+" "+slit, prefix+lit+" "+dlit)
-				if err != nil {
-					return
-				}
+				d.add(kind, lit, DiffSignature, specCompat(spec, targ), slit, dlit)
 				continue
 			}
 			// 文档
-			slit, dlit = SpecDoc(spec).Text(), SpecDoc(targ).Text()
-
-			if slit != dlit {
-				diff, err = true, diffOut(false, w, prefix+lit+" doc:\n\n"+slit, prefix+lit+" doc:\n\n"+dlit)
-			}
-			if err != nil {
-				return
-			}
+			d.doc(kind, lit, specDoc(decl, spec).Text(), specDoc(tdecl, targ).Text())
 		}
 	}
 	// 第二次只对比没有的
-	ss, dd = dd, ss
-	for _, node := range ss {
-		decl := node.(*ast.GenDecl)
-		for _, spec := range decl.Specs {
-			lit = SpecIdentLit(spec)
+	for _, node := range dd {
+		for _, spec := range node.(*ast.GenDecl).Specs {
+			lit := SpecIdentLit(spec)
 			if lit == "_" {
 				continue
 			}
-			targ, _, _ := dd.SearchSpec(lit)
-			if targ == nil {
-				diff, err = true, diffOut(false, w, "", prefix+lit)
-				if err != nil {
-					return
-				}
+			if targ, _, _ := ss.SearchSpec(lit); targ == nil {
+				d.add(kind, lit, DiffRemoved, Breaking, "", typeLit(lit, SpecTypeLit(spec)))
 			}
 		}
 	}
-	return
 }
 
-func diffFuncDecls(w io.Writer, prefix string, source, target []ast.Decl) (diff bool, err error) {
+// specDoc 返回 decl 中 spec 的文档, 无括号的单个 spec 使用 decl 的文档.
+func specDoc(decl *ast.GenDecl, spec ast.Spec) *ast.CommentGroup {
+	if doc := SpecDoc(spec); doc != nil || decl.Lparen.IsValid() {
+		return doc
+	}
+	return decl.Doc
+}
+
+func (d *differ) funcDecls(kind string, source, target []ast.Decl) {
 	ss := SortDecl(source)
 	dd := SortDecl(target)
-	if ss.Len() == 0 && dd.Len() == 0 {
-		return
-	}
-	if ss.Len() == 0 || dd.Len() == 0 {
-		return TextDiff(w, prefix+strconv.Itoa(ss.Len()), prefix+strconv.Itoa(dd.Len()))
-	}
-	var lit string
 	for _, node := range ss {
 		spec := node.(*ast.FuncDecl)
-		lit = FuncIdentLit(spec)
-		targ := dd.Search(lit)
+		lit := FuncIdentLit(spec)
+		slit := FuncLit(spec)
+		targ := dd.SearchFunc(lit)
 		if targ == nil {
-			diff, err = true, diffOut(false, w, FuncLit(spec), "")
-			if err != nil {
-				return
-			}
+			d.add(kind, lit, DiffAdded, Compatible, slit, "")
 			continue
 		}
-		slit, dlit := FuncLit(spec), FuncLit(targ.(*ast.FuncDecl))
-		if slit != dlit {
-			diff, err = true, diffOut(false, w, slit, dlit)
-			if err != nil {
-				return
-			}
+		dlit := FuncLit(targ)
+		if recvParamsKey(spec, slit) != recvParamsKey(targ, dlit) {
+			d.add(kind, lit, DiffSignature, funcCompat(spec, targ), slit, dlit)
 			continue
 		}
-		sdoc, ddoc := spec.Doc.Text(), targ.(*ast.FuncDecl).Doc.Text()
-		if sdoc != ddoc {
-			diff, err = true, diffOut(false, w, slit+"\n\n"+sdoc, dlit+"\n\n"+ddoc)
-			if err != nil {
-				return
-			}
-		}
+		d.doc(kind, lit, spec.Doc.Text(), targ.Doc.Text())
 	}
-	ss, dd = dd, ss
-	for _, node := range ss {
+	for _, node := range dd {
 		spec := node.(*ast.FuncDecl)
-		lit = FuncIdentLit(spec)
-		targ := dd.Search(lit)
-		if targ == nil {
-			diff, err = true, diffOut(false, w, "", FuncLit(spec))
-			if err != nil {
-				return
-			}
+		lit := FuncIdentLit(spec)
+		if ss.SearchFunc(lit) == nil {
+			d.add(kind, lit, DiffRemoved, Breaking, "", FuncLit(spec))
 		}
 	}
-	return
 }
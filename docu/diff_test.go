@@ -0,0 +1,230 @@
+package docu
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestDiffs(t *testing.T) {
+	const src = `package p
+
+// A is a.
+const A = 1
+
+const B = 2
+
+// F does f.
+func F(a int) error
+
+// G does g.
+func G()
+
+// T is t.
+type T struct{}
+
+// M does m.
+// It is good.
+func (t *T) M()
+`
+	const dst = `package p
+
+// A is
+// a.
+const A = 1
+
+const C = 3
+
+// F does f.
+func F(b int) error
+
+// G does
+// nothing.
+func G()
+
+// T is t.
+type T struct{}
+
+// M does m. It is good.
+func (t *T) M()
+`
+	fset := token.NewFileSet()
+	source, err := parser.ParseFile(fset, "src.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := parser.ParseFile(fset, "dst.go", dst, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Index(source)
+	Index(target)
+
+	want := []DiffRecord{
+		{"p", "const", "A", DiffForm, Cosmetic, "A is a.\n", "A is\na.\n"},
+		{"p", "const", "B", DiffAdded, Compatible, "B", ""},
+		{"p", "const", "C", DiffRemoved, Breaking, "", "C"},
+		{"p", "func", "F", DiffSignature, Cosmetic, "func F(a int) error", "func F(b int) error"},
+		{"p", "func", "G", DiffDoc, Cosmetic, "G does g.\n", "G does\nnothing.\n"},
+		{"p", "method", "*T.M", DiffForm, Cosmetic, "M does m.\nIt is good.\n", "M does m. It is good.\n"},
+	}
+	got := Diffs(source, target, false)
+	if len(got) != len(want) {
+		t.Fatalf("Diffs got %d records, want %d:\n%v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Diffs[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	got = Diffs(source, target, true)
+	if len(got) != 1 || got[0] != want[0] {
+		t.Errorf("first Diffs = %+v, want %+v", got, want[:1])
+	}
+}
+
+func TestDiffsCompat(t *testing.T) {
+	const src = `package p
+
+type S struct {
+	A    int
+	B    string
+	c    bool
+	Func func(x int) error
+}
+
+type U struct {
+	A int
+	b int
+}
+
+type I interface {
+	M(n int) error
+	N()
+}
+
+type J interface{ M() }
+
+var V int64
+
+func F(ctx string) (n int, err error)
+
+func (s S) M()
+`
+	const dst = `package p
+
+type S struct {
+	A    int
+	Func func(int) error
+}
+
+type U struct {
+	A int
+	c int
+}
+
+type I interface {
+	M(i int) error
+	N()
+}
+
+type J interface{ M(); N() }
+
+var V int
+
+func F(s string) (int, error)
+
+func (s S) M() error
+`
+	fset := token.NewFileSet()
+	source, err := parser.ParseFile(fset, "src.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := parser.ParseFile(fset, "dst.go", dst, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Index(source)
+	Index(target)
+
+	want := map[string]string{
+		"S":   Compatible,
+		"U":   Cosmetic,
+		"I":   Cosmetic,
+		"J":   Breaking,
+		"V":   Breaking,
+		"F":   Cosmetic,
+		"S.M": Breaking,
+	}
+	records := Diffs(source, target, false)
+	for _, r := range records {
+		if r.Change != DiffSignature {
+			t.Errorf("unexpected %+v", r)
+			continue
+		}
+		ident := strings.TrimPrefix(r.Ident, "*")
+		if r.Compat != want[ident] {
+			t.Errorf("%s Compat = %s, want %s", r.Ident, r.Compat, want[ident])
+		}
+		delete(want, ident)
+	}
+	for ident := range want {
+		t.Errorf("missing record of %s", ident)
+	}
+
+	s := SummarizeDiffs(records)
+	if s.Package != "p" || s.Breaking != 3 || s.Compatible != 1 || s.Cosmetic != 3 {
+		t.Errorf("SummarizeDiffs = %+v", s)
+	}
+	if n := len(BreakingDiffs(records)); n != 3 {
+		t.Errorf("BreakingDiffs got %d records, want 3", n)
+	}
+}
+
+func TestDiffsRecvTypeParams(t *testing.T) {
+	const src = `package p
+
+type List[T any] struct{}
+
+func (l *List[T]) Len() int
+
+func (l *List[T]) Push(v T) *list.T
+
+func (l *List[T]) Get(i int) T
+`
+	const dst = `package p
+
+type List[E any] struct{}
+
+func (l *List[E]) Len() int
+
+func (l *List[E]) Push(x E) *list.T
+
+func (l *List[E]) Get(i int) int
+`
+	fset := token.NewFileSet()
+	source, err := parser.ParseFile(fset, "src.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := parser.ParseFile(fset, "dst.go", dst, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Index(source)
+	Index(target)
+
+	var methods []DiffRecord
+	for _, r := range Diffs(source, target, false) {
+		if r.Kind == "method" {
+			methods = append(methods, r)
+		}
+	}
+	if len(methods) != 2 || methods[0].Ident != "*List.Get" || methods[0].Compat != Breaking ||
+		methods[1].Ident != "*List.Push" || methods[1].Compat != Cosmetic {
+		t.Errorf("Diffs = %+v", methods)
+	}
+}
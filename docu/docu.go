@@ -12,25 +12,108 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 
 	"golang.org/x/tools/godoc/vfs"
 )
 
 // Docu 复合 token.FileSet, ast.Package 提供 Go doc 支持.
+//
+// Docu 的方法可被多个 goroutine 并发调用, 但不应同时解析和合并同一个包,
+// 字段应在并发使用之前设置好.
 type Docu struct {
 	parser.Mode
 	FileSet *token.FileSet
+	// mu 保护 astpkg 及其包含的 ast.Package.Files.
+	mu sync.Mutex
 	// astpkg 的 key 就是 import paths.
 	astpkg map[string]*ast.Package
 	// Filter 用于生成 astpkg 时过滤文件名和包名.
 	// 显然文件名包含后缀 ".go", 包名则没有.
 	Filter func(name string) bool
+	// GOOS, GOARCH 为目标平台, 用于过滤文件名后缀和构建约束.
+	// 空值表示不限.
+	GOOS, GOARCH string
+	// CgoEnabled 表示构建标签 "cgo" 是否成立.
+	CgoEnabled bool
+	// ReleaseTags 为成立的 "go1.N" 版本标签.
+	ReleaseTags []string
+	// Tags 为额外成立的构建标签.
+	Tags []string
+	// Examples 表示是否从 "_test.go" 文件提取示例函数, 参见 IsExample.
+	// 示例函数作为顶级函数声明合并到包中, 排在方法之后.
+	Examples bool
+	// examples 的 key 为 import paths, 值为各测试文件中的示例函数.
+	examples map[string]map[string]*ast.File
 }
 
-// New 返回使用 DefaultFilter 进行过滤的 Docu 实例.
+// New 返回使用 DefaultFilter 进行过滤, 构建标签取自包级变量的 Docu 实例.
 func New() *Docu {
-	return &Docu{parser.ParseComments, token.NewFileSet(),
-		make(map[string]*ast.Package), DefaultFilter}
+	return &Docu{
+		Mode:        parser.ParseComments,
+		FileSet:     token.NewFileSet(),
+		astpkg:      make(map[string]*ast.Package),
+		Filter:      DefaultFilter,
+		GOOS:        GOOS,
+		GOARCH:      GOARCH,
+		CgoEnabled:  CgoEnabled,
+		ReleaseTags: ReleaseTags,
+		Tags:        Tags,
+		Examples:    Examples,
+	}
+}
+
+// MatchOSArch 返回 Go 文件名 name 的 goos, goarch 后缀是否符合目标平台.
+// Docu 命名风格的文件总是符合.
+func (du *Docu) MatchOSArch(name string) bool {
+	if IsNormalName(name) {
+		return true
+	}
+	goos, goarch, _ := OSArchTest(name)
+	return (goos == "" || du.matchOS(goos)) &&
+		(goarch == "" || du.GOARCH == "" || goarch == du.GOARCH)
+}
+
+// matchOS 返回 goos 是否符合目标平台, 含 go/build 的隐含关系,
+// 比如 android 兼容 linux.
+func (du *Docu) matchOS(goos string) bool {
+	switch {
+	case du.GOOS == "" || goos == du.GOOS:
+		return true
+	case goos == "linux":
+		return du.GOOS == "android"
+	case goos == "solaris":
+		return du.GOOS == "illumos"
+	case goos == "darwin":
+		return du.GOOS == "ios"
+	}
+	return false
+}
+
+// MatchTag 返回构建标签 tag 对 du 是否成立.
+// GOOS 或 GOARCH 为空时, 任何可识别的操作系统或体系结构名都成立.
+func (du *Docu) MatchTag(tag string) bool {
+	switch {
+	case tag == "cgo":
+		return du.CgoEnabled
+	case tag == "unix":
+		return du.GOOS == "" || contains(unixList, du.GOOS)
+	case IsKnownOS(tag):
+		return du.matchOS(tag)
+	case IsKnownArch(tag):
+		return du.GOARCH == "" || tag == du.GOARCH
+	}
+	for _, s := range du.ReleaseTags {
+		if tag == s {
+			return true
+		}
+	}
+	for _, s := range du.Tags {
+		if tag == s {
+			return true
+		}
+	}
+	return false
 }
 
 // Package 返回 key 对应的 *ast.Package.
@@ -39,7 +122,9 @@ func (du *Docu) Package(key string) *ast.Package {
 	if du == nil {
 		return nil
 	}
+	du.mu.Lock()
 	pkg, ok := du.astpkg[key]
+	du.mu.Unlock()
 	if !ok || pkg == nil {
 		return nil
 	}
@@ -82,13 +167,11 @@ func IsGodocuFile(file *ast.File) bool {
 // MergePackageFiles 合并 import paths 的包为一个 ast.File 文件, 并用 Index 排序.
 // 如果该 file 是 Godocu 文件命名风格, 设置 file.Unresolved[0] = GodocuStyle.
 func (du *Docu) MergePackageFiles(key string) (file *ast.File) {
-	if du == nil || len(du.astpkg) == 0 {
-		return nil
-	}
-	pkg, ok := du.astpkg[key]
-	if !ok || pkg == nil {
+	pkg := du.Package(key)
+	if pkg == nil {
 		return
 	}
+	pkg = du.withExamples(key, pkg)
 	// 单文件优化
 	if len(pkg.Files) == 1 {
 		var name string
@@ -103,8 +186,15 @@ func (du *Docu) MergePackageFiles(key string) (file *ast.File) {
 			ast.FilterFuncDuplicates|ast.FilterUnassociatedComments|ast.FilterImportDuplicates)
 		// 取出 License 和 import paths 放到 file.Comments
 		// 通常 License 总第一个
+		// 按文件名次序, 保证结果稳定
 		var lic, imp *ast.CommentGroup
-		for _, f := range pkg.Files {
+		names := make([]string, 0, len(pkg.Files))
+		for name := range pkg.Files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			f := pkg.Files[name]
 			offset := f.Name.Pos() + token.Pos(len(file.Name.String())) + 1
 			for _, comm := range f.Comments {
 				at := comm.Pos() - offset
@@ -136,6 +226,12 @@ func (du *Docu) MergePackageFiles(key string) (file *ast.File) {
 		if imp != nil {
 			file.Comments = append(file.Comments, imp)
 		}
+		// 保留 BUG(who) 这样的注释, 参见 Notes
+		files := make([]*ast.File, len(names))
+		for i, name := range names {
+			files[i] = pkg.Files[name]
+		}
+		file.Comments = append(file.Comments, packageNotes(files)...)
 	}
 
 	sort.Sort(SortImports(file.Imports))
@@ -213,9 +309,18 @@ func (du *Docu) readFileInfo(abs string) ([]os.FileInfo, error) {
 		return nil, err
 	}
 	defer fd.Close()
-	return fd.Readdir(-1)
+	info, err := fd.Readdir(-1)
+	// 按文件名排序, 保证多文件包的源码次序稳定
+	sort.Sort(sortFileInfo(info))
+	return info, err
 }
 
+type sortFileInfo []os.FileInfo
+
+func (s sortFileInfo) Len() int           { return len(s) }
+func (s sortFileInfo) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s sortFileInfo) Less(i, j int) bool { return s[i].Name() < s[j].Name() }
+
 func (du *Docu) parseFromVfs(fs vfs.FileSystem, dir string,
 	info []os.FileInfo) (importPaths string, err error) {
 
@@ -223,13 +328,18 @@ func (du *Docu) parseFromVfs(fs vfs.FileSystem, dir string,
 	var paths string
 
 	for _, info := range info {
-		if info.IsDir() || !strings.HasSuffix(info.Name(), ".go") ||
-			!du.filter(info.Name()) {
-
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".go") {
 			continue
 		}
+		parse := du.parseFile
+		if !du.filter(info.Name()) {
+			if !du.Examples || !strings.HasSuffix(info.Name(), "_test.go") {
+				continue
+			}
+			parse = du.parseExamples
+		}
 		if r, err = fs.Open(info.Name()); err == nil {
-			paths, err = du.parseFile(dir, info.Name(), r)
+			paths, err = parse(dir, info.Name(), r)
 			if err == nil {
 				err = r.Close()
 			} else {
@@ -255,6 +365,9 @@ func (du *Docu) filter(name string) bool {
 func (du *Docu) parseFile(abs, name string, src interface{}) (string, error) {
 	var bs []byte
 	var err error
+	if !du.MatchOSArch(name) {
+		return "", nil
+	}
 	importPaths := LookImportPath(abs)
 	if importPaths == "" {
 		return "", errors.New("LookImportPath fail: " + abs)
@@ -265,7 +378,7 @@ func (du *Docu) parseFile(abs, name string, src interface{}) (string, error) {
 		return "", err
 	}
 
-	if !IsNormalName(name) && !buildForLinux(bs) {
+	if !IsNormalName(name) && !buildFor(bs, du.MatchTag) {
 		return "", nil
 	}
 
@@ -273,6 +386,10 @@ func (du *Docu) parseFile(abs, name string, src interface{}) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	// 翻译文档中由 Examples 输出的示例函数, 源文件中的同名函数不是示例
+	if IsNormalName(name) {
+		textExamples(du.FileSet, astfile)
+	}
 
 	name = astfile.Name.String()
 	// 包名过滤
@@ -280,6 +397,8 @@ func (du *Docu) parseFile(abs, name string, src interface{}) (string, error) {
 		return "", nil
 	}
 
+	du.mu.Lock()
+	defer du.mu.Unlock()
 	pkg, ok := du.astpkg[importPaths]
 	if !ok {
 		pkg = &ast.Package{
@@ -1,6 +1,7 @@
 package docu
 
 import (
+	"go/build"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -9,12 +10,43 @@ import (
 var (
 	GOROOT  = strOr(os.Getenv("GOROOT"), runtime.GOROOT())
 	GOPATHS = filepath.SplitList(os.Getenv("GOPATH"))
+	// GOMODCACHE 是 module cache 目录, 缺省为 GOPATH/pkg/mod.
+	GOMODCACHE = strOr(os.Getenv("GOMODCACHE"), defaultModCache())
+)
+
+// Roots 为额外的文档基础目录, 其下子目录的相对路径即 import paths.
+// 优先于 go.mod 和 GOPATH 风格计算 import paths, 通常用于 target.
+var Roots []string
+
+// GOOS, GOARCH 为 New 生成 Docu 时缺省的目标平台.
+// 多平台文档只提取该组合, 文件名后缀和构建约束都依此过滤.
+var (
+	GOOS   = "linux"
+	GOARCH = "amd64"
+)
+
+// CgoEnabled, ReleaseTags, Tags 为 New 生成 Docu 时缺省的构建标签.
+var (
+	CgoEnabled  = true
+	ReleaseTags = build.Default.ReleaseTags
+	Tags        []string
 )
 
 // via go/build/syslist.go
 
-const goosList = "android darwin dragonfly freebsd linux nacl netbsd openbsd plan9 solaris windows "
-const goarchList = "386 amd64 amd64p32 arm armbe arm64 arm64be ppc64 ppc64le mips mipsle mips64 mips64le mips64p32 mips64p32le ppc s390 s390x sparc sparc64 "
+const goosList = "aix android darwin dragonfly freebsd hurd illumos ios js linux nacl netbsd openbsd plan9 solaris wasip1 windows zos "
+const unixList = "aix android darwin dragonfly freebsd hurd illumos ios linux netbsd openbsd solaris "
+const goarchList = "386 amd64 amd64p32 arm armbe arm64 arm64be loong64 mips mipsle mips64 mips64le mips64p32 mips64p32le ppc ppc64 ppc64le riscv riscv64 s390 s390x sparc sparc64 wasm "
+
+// IsKnownOS 返回 goos 是否为可识别的操作系统名.
+func IsKnownOS(goos string) bool {
+	return goos != "" && contains(goosList, goos)
+}
+
+// IsKnownArch 返回 goarch 是否为可识别的体系结构名.
+func IsKnownArch(goarch string) bool {
+	return goarch != "" && contains(goarchList, goarch)
+}
 
 // Warehouse 为预定义托管仓库域名.
 // 因托管商差异, 依照 Part 计算的仓库地址不一定正确.
@@ -32,6 +64,22 @@ var Warehouse = []struct {
 	{"git.oschina.net", 2},
 }
 
+func defaultModCache() string {
+	gopath := os.Getenv("GOPATH")
+	if gopath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		gopath = filepath.Join(home, "go")
+	}
+	list := filepath.SplitList(gopath)
+	if len(list) == 0 {
+		return ""
+	}
+	return filepath.Join(list[0], "pkg", "mod")
+}
+
 func strOr(a, b string) string {
 	if a != "" {
 		return a
@@ -56,8 +104,8 @@ func existsFile(path string) bool {
 
 // Abs 返回 path 的绝对路径.
 // 如果 path 疑似绝对路径返回 path.
-// 否则在 GOROOT, GOPATHS 中搜索 path 并返回绝对路径.
-// 如果未找到返回 path.
+// 否则在 GOROOT, GOPATHS, 当前 module 及其 require 的 module cache 中
+// 搜索 path 并返回绝对路径. 如果未找到返回 path.
 func Abs(path string) string {
 	if path == "" || filepath.IsAbs(path) {
 		return path
@@ -80,5 +128,9 @@ func Abs(path string) string {
 		}
 	}
 
+	if abs := moduleAbs(path); abs != "" {
+		return abs
+	}
+
 	return path
 }
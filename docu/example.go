@@ -0,0 +1,216 @@
+package docu
+
+import (
+	"bytes"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Examples 为 New 生成 Docu 时是否从 "_test.go" 文件提取示例函数.
+var Examples bool
+
+// IsExample 返回 fn 是否为 go test 风格的示例函数, 比如 ExampleReader_Read.
+func IsExample(fn *ast.FuncDecl) bool {
+	if fn == nil || fn.Recv != nil || fn.Name == nil ||
+		!strings.HasPrefix(fn.Name.Name, "Example") {
+		return false
+	}
+	if rest := fn.Name.Name[len("Example"):]; rest != "" {
+		r, _ := utf8.DecodeRuneInString(rest)
+		if unicode.IsLower(r) {
+			return false
+		}
+	}
+	ft := fn.Type
+	return ft.TypeParams == nil && ft.Results == nil &&
+		(ft.Params == nil || len(ft.Params.List) == 0)
+}
+
+// splitExample 同 go/doc, 分割示例函数名 name 为所属声明的标识符和后缀.
+// 标识符形如 "", "F", "T", "T.M", 后缀以小写字母开头.
+func splitExample(name string) (ident, suffix string) {
+	ident = strings.TrimPrefix(name, "Example")
+	if i := strings.LastIndexByte(ident, '_'); i != -1 {
+		r, _ := utf8.DecodeRuneInString(ident[i+1:])
+		if unicode.IsLower(r) {
+			ident, suffix = ident[:i], ident[i+1:]
+		}
+	}
+	return strings.Replace(ident, "_", ".", 1), suffix
+}
+
+// ExampleIdent 返回示例函数 fn 所属声明的标识符, 同 Anchor, 包的示例为 "".
+func ExampleIdent(fn *ast.FuncDecl) string {
+	ident, _ := splitExample(fn.Name.Name)
+	return ident
+}
+
+// ExampleSuffix 返回示例函数 fn 的后缀, 比如 ExampleReader_Read_second 的 "second".
+func ExampleSuffix(fn *ast.FuncDecl) string {
+	_, suffix := splitExample(fn.Name.Name)
+	return suffix
+}
+
+// exampleBody 返回以代码 code 为唯一语句的函数体.
+// 解析时示例函数体被转换为代码文本, 以便脱离 FileSet 和 Comments 输出.
+func exampleBody(body *ast.BlockStmt, code string) *ast.BlockStmt {
+	return &ast.BlockStmt{
+		Lbrace: body.Lbrace,
+		List: []ast.Stmt{&ast.ExprStmt{
+			X: &ast.BasicLit{ValuePos: body.Lbrace, Kind: token.STRING, Value: code},
+		}},
+		Rbrace: body.Rbrace,
+	}
+}
+
+// exampleText 返回已转换为代码文本的示例函数 fn 的函数体代码.
+func exampleText(fn *ast.FuncDecl) (string, bool) {
+	if fn.Body == nil || len(fn.Body.List) != 1 {
+		return "", false
+	}
+	if es, ok := fn.Body.List[0].(*ast.ExprStmt); ok {
+		if lit, ok := es.X.(*ast.BasicLit); ok && lit.ValuePos == fn.Body.Lbrace {
+			return lit.Value, true
+		}
+	}
+	return "", false
+}
+
+// isTextExample 返回 fn 是否为 textExamples 转换过的示例函数, 即来自 "_test.go" 文件
+// 或翻译文档. 普通源文件中的同名函数, 比如 ExampleHandler, 不是示例.
+func isTextExample(fn *ast.FuncDecl) bool {
+	if !IsExample(fn) {
+		return false
+	}
+	_, ok := exampleText(fn)
+	return ok
+}
+
+// exampleBlock 返回示例函数 fn 含花括号的函数体代码.
+func exampleBlock(fn *ast.FuncDecl) string {
+	if fn.Body == nil {
+		return ""
+	}
+	if code, ok := exampleText(fn); ok {
+		return code
+	}
+	var buf bytes.Buffer
+	gofmtConfig.Fprint(&buf, emptyfset, fn.Body)
+	return buf.String()
+}
+
+// ExampleCode 返回示例函数 fn 的代码, 不含花括号和一级缩进, 含 "// Output:" 注释.
+// 同 FuncLit, 末尾没有换行.
+func ExampleCode(fn *ast.FuncDecl) string {
+	code := strings.TrimSpace(exampleBlock(fn))
+	code = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(code, "{"), "}"))
+	if code == "" {
+		return ""
+	}
+	lines := strings.Split(code, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, "\t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// textExamples 把 file 中示例函数的函数体转换为代码文本, 并从 file.Comments
+// 中剔除函数体内的注释. 返回 file 中的示例函数.
+func textExamples(fset *token.FileSet, file *ast.File) (examples []*ast.FuncDecl) {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || !IsExample(fn) || fn.Body == nil {
+			continue
+		}
+		examples = append(examples, fn)
+		if _, ok = exampleText(fn); ok {
+			continue
+		}
+
+		var inner, outer []*ast.CommentGroup
+		for _, c := range file.Comments {
+			if c != nil && c.Pos() > fn.Body.Lbrace && c.End() < fn.Body.Rbrace {
+				inner = append(inner, c)
+			} else {
+				outer = append(outer, c)
+			}
+		}
+		var buf bytes.Buffer
+		gofmtConfig.Fprint(&buf, fset, &printer.CommentedNode{Node: fn.Body, Comments: inner})
+		fn.Body = exampleBody(fn.Body, buf.String())
+		file.Comments = outer
+	}
+	return
+}
+
+// parseExamples 解析 "_test.go" 文件 name 中的示例函数, 作为所在目录包的示例.
+// 测试文件的包名可以带 "_test" 后缀. 返回值同 parseFile, 总是返回空 import paths.
+func (du *Docu) parseExamples(abs, name string, src interface{}) (string, error) {
+	if !du.MatchOSArch(name) {
+		return "", nil
+	}
+	importPaths := LookImportPath(abs)
+	if importPaths == "" {
+		return "", errors.New("LookImportPath fail: " + abs)
+	}
+	abs = filepath.Join(abs, name)
+	bs, err := readSource(abs, src)
+	if err != nil || !buildFor(bs, du.MatchTag) {
+		return "", err
+	}
+	astfile, err := parser.ParseFile(du.FileSet, abs, bs, parser.ParseComments)
+	if err != nil {
+		return "", err
+	}
+	examples := textExamples(du.FileSet, astfile)
+	if len(examples) == 0 {
+		return "", nil
+	}
+
+	file := &ast.File{Name: astfile.Name, Decls: make([]ast.Decl, len(examples))}
+	for i, fn := range examples {
+		file.Decls[i] = fn
+	}
+
+	du.mu.Lock()
+	defer du.mu.Unlock()
+	if du.examples == nil {
+		du.examples = make(map[string]map[string]*ast.File)
+	}
+	files := du.examples[importPaths]
+	if files == nil {
+		files = make(map[string]*ast.File)
+		du.examples[importPaths] = files
+	}
+	if _, ok := files[abs]; ok {
+		return "", errors.New("Duplicates: " + abs)
+	}
+	files[abs] = file
+	return "", nil
+}
+
+// withExamples 返回包含 key 对应示例的 pkg 副本, 没有示例时返回 pkg.
+func (du *Docu) withExamples(key string, pkg *ast.Package) *ast.Package {
+	du.mu.Lock()
+	files := du.examples[key]
+	du.mu.Unlock()
+	if len(files) == 0 {
+		return pkg
+	}
+	cp := &ast.Package{Name: pkg.Name, Files: make(map[string]*ast.File)}
+	for abs, file := range pkg.Files {
+		cp.Files[abs] = file
+	}
+	for abs, file := range files {
+		// 测试文件的包名可能带 "_test" 后缀
+		cp.Files[abs] = &ast.File{Name: ast.NewIdent(pkg.Name), Decls: file.Decls}
+	}
+	return cp
+}
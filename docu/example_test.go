@@ -0,0 +1,125 @@
+package docu
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitExample(t *testing.T) {
+	for _, tt := range []struct{ name, ident, suffix string }{
+		{"Example", "", ""},
+		{"Example_second", "", "second"},
+		{"ExampleNew", "New", ""},
+		{"ExampleReader_Read", "Reader.Read", ""},
+		{"ExampleReader_Read_second", "Reader.Read", "second"},
+	} {
+		ident, suffix := splitExample(tt.name)
+		if ident != tt.ident || suffix != tt.suffix {
+			t.Errorf("splitExample(%q) = %q, %q", tt.name, ident, suffix)
+		}
+	}
+
+	const src = `package p
+func Example() {}
+func ExampleA(t int) {}
+func Examples() {}
+func Examplea() {}
+func (T) ExampleB() {}
+`
+	file, err := parser.ParseFile(token.NewFileSet(), "p_test.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, decl := range file.Decls {
+		if is := IsExample(decl.(*ast.FuncDecl)); is != (i == 0) {
+			t.Errorf("IsExample %d = %v", i, is)
+		}
+	}
+}
+
+func TestExamples(t *testing.T) {
+	du := New()
+	du.Examples = true
+	key, err := du.Parse(filepath.Join(GOROOT, "src", "strings"), nil)
+	if err != nil || key == "" {
+		t.Skip("strings not found in GOROOT")
+	}
+	file := du.MergePackageFiles(key)
+	ExportedFileFilter(file)
+	g := GroupNormal(file.Decls)
+	for _, decl := range g.Funcs {
+		if IsExample(decl.(*ast.FuncDecl)) {
+			t.Fatalf("GroupNormal Funcs has example %s", FuncIdentLit(decl.(*ast.FuncDecl)))
+		}
+	}
+	examples := g.ExamplesOf("Builder")
+	if len(examples) == 0 {
+		t.Fatal("ExamplesOf Builder not found")
+	}
+	code := ExampleCode(examples[0])
+	if !strings.Contains(code, "// Output:") || strings.HasPrefix(code, "\t") {
+		t.Errorf("ExampleCode =\n%s", code)
+	}
+
+	// 输出的示例函数可以再次解析
+	var buf bytes.Buffer
+	if err = Fprint(&buf, file); err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	doc, err := parser.ParseFile(fset, "doc.go", buf.Bytes(), parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed := textExamples(fset, doc)
+	if len(parsed) != len(g.Examples) {
+		t.Fatalf("textExamples = %d, want %d", len(parsed), len(g.Examples))
+	}
+	for i, fn := range parsed {
+		if ExampleCode(fn) != ExampleCode(g.Examples[i]) {
+			t.Errorf("%s:\n%s\nwant\n%s", fn.Name, ExampleCode(fn), ExampleCode(g.Examples[i]))
+		}
+	}
+}
+
+func TestSourceExampleName(t *testing.T) {
+	const src = `package p
+
+// ExampleHandler is a handler.
+func ExampleHandler() {
+	// serve
+	serve()
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := file.Decls[0].(*ast.FuncDecl)
+	if NodeNumber(fn) != FuncNum || len(GroupNormal(file.Decls).Funcs) != 1 {
+		t.Fatalf("ExampleHandler in source is classified as an example")
+	}
+	var buf bytes.Buffer
+	if err = Fprint(&buf, file); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); strings.Contains(out, "serve") {
+		t.Errorf("Fprint =\n%s", out)
+	}
+	target, _ := parser.ParseFile(fset, "dst.go", "package p\n", parser.ParseComments)
+	if records := Diffs(file, target, false); len(records) != 1 || records[0].Ident != "ExampleHandler" {
+		t.Errorf("Diffs = %+v", records)
+	}
+
+	// 来自测试文件的同名函数是示例
+	textExamples(fset, file)
+	if NodeNumber(fn) != ExampleNum {
+		t.Errorf("NodeNumber = %d, want ExampleNum", NodeNumber(fn))
+	}
+}
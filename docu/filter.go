@@ -183,19 +183,12 @@ func exportedTypeSpecFilter(n *ast.TypeSpec, by SortDecl) bool {
 // exportedRecvFilter 该方法仅仅适用于检测 ast.FuncDecl.Recv 是否导出
 func exportedRecvFilter(fieldList *ast.FieldList, by SortDecl) bool {
 	for i := 0; i < len(fieldList.List); i++ {
-		switch n := fieldList.List[i].Type.(type) {
-		case *ast.Ident:
-			if spec, _, _ := by.SearchSpec(n.String()); !n.IsExported() && spec == nil {
-				return false
-			}
-		case *ast.StarExpr:
-			ident, ok := n.X.(*ast.Ident)
-			if !ok {
-				return false
-			}
-			if spec, _, _ := by.SearchSpec(ident.String()); !ident.IsExported() && spec == nil {
-				return false
-			}
+		ident := recvTypeIdent(fieldList.List[i].Type)
+		if ident == nil {
+			return false
+		}
+		if spec, _, _ := by.SearchSpec(ident.String()); !ident.IsExported() && spec == nil {
+			return false
 		}
 	}
 	return true
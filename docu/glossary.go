@@ -0,0 +1,143 @@
+package docu
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Term 表示术语表中的一个术语.
+type Term struct {
+	Source    string   // 原文术语, 匹配时忽略大小写和复数形式
+	Target    string   // 约定的译法
+	Forbidden []string // 禁用的译法
+}
+
+// Glossary 为术语表, 文本格式为每行一个术语, "#" 开头的行为注释:
+//
+//	原文术语 = 约定译法 [! 禁用译法]...
+//
+// 例如:
+//
+//	goroutine = goroutine
+//	slice = 切片 ! 片段 ! 分片
+//	interface = 接口
+type Glossary []Term
+
+// ParseGlossary 从 r 读取文本格式的术语表.
+func ParseGlossary(r io.Reader) (g Glossary, err error) {
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		i := strings.IndexByte(line, '=')
+		if i == -1 {
+			return nil, fmt.Errorf("glossary:%d: missing =", n)
+		}
+		t := Term{Source: strings.TrimSpace(line[:i])}
+		fields := strings.Split(line[i+1:], "!")
+		t.Target = strings.TrimSpace(fields[0])
+		for _, s := range fields[1:] {
+			if s = strings.TrimSpace(s); s != "" {
+				t.Forbidden = append(t.Forbidden, s)
+			}
+		}
+		if t.Source == "" || t.Target == "" {
+			return nil, fmt.Errorf("glossary:%d: empty term", n)
+		}
+		g = append(g, t)
+	}
+	return g, scanner.Err()
+}
+
+// ReadGlossary 读取文件 filename 中文本格式的术语表.
+func ReadGlossary(filename string) (Glossary, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseGlossary(f)
+}
+
+// 术语检查的问题类别
+const (
+	TermMissing   = "missing"   // 原文含术语, 译文缺少约定译法
+	TermForbidden = "forbidden" // 译文使用了禁用译法
+)
+
+// GlossaryRecord 表示术语检查发现的一个问题.
+type GlossaryRecord struct {
+	Package string // 包名, 调用者可替换为 import paths
+	Ident   string // 标识符, 包文档为 "package", 字段形如 "Type.Field"
+	Kind    string // TermMissing 或 TermForbidden
+	Term    string // 原文术语
+	Target  string // 约定译法
+	Variant string `json:",omitempty"` // 使用的禁用译法
+}
+
+// CheckGlossary 以术语表 g 检查翻译文档 file 中已翻译的文档, 返回发现的问题.
+// 原文中的代码块被忽略. Godocu 风格文档会调用 ClearComments.
+func CheckGlossary(file *ast.File, g Glossary) (records []GlossaryRecord) {
+	pkg := file.Name.String()
+	eachTrans(file, func(ident, origin, trans string, _ *ast.CommentGroup) {
+		if trans == "" {
+			return
+		}
+		origin = strings.ToLower(strings.Join(textBlocks(origin), "\n"))
+		lower := strings.ToLower(trans)
+		for _, t := range g {
+			if !containsTerm(origin, strings.ToLower(t.Source)) {
+				continue
+			}
+			if !strings.Contains(lower, strings.ToLower(t.Target)) {
+				records = append(records,
+					GlossaryRecord{pkg, ident, TermMissing, t.Source, t.Target, ""})
+			}
+			for _, v := range t.Forbidden {
+				if strings.Contains(lower, strings.ToLower(v)) {
+					records = append(records,
+						GlossaryRecord{pkg, ident, TermForbidden, t.Source, t.Target, v})
+				}
+			}
+		}
+	})
+	return
+}
+
+// containsTerm 返回 text 是否含有单词 term 或其复数形式, 两者都应是小写.
+func containsTerm(text, term string) bool {
+	for i := 0; ; {
+		pos := strings.Index(text[i:], term)
+		if pos == -1 {
+			return false
+		}
+		pos += i
+		i = pos + len(term)
+		r, _ := utf8.DecodeLastRuneInString(text[:pos])
+		if pos != 0 && isWordRune(r) {
+			continue
+		}
+		rest := text[i:]
+		if strings.HasPrefix(rest, "es") {
+			rest = rest[2:]
+		} else if strings.HasPrefix(rest, "s") {
+			rest = rest[1:]
+		}
+		r, _ = utf8.DecodeRuneInString(rest)
+		if rest == "" || !isWordRune(r) {
+			return true
+		}
+	}
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
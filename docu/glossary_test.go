@@ -0,0 +1,61 @@
+package docu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckGlossary(t *testing.T) {
+	g, err := ParseGlossary(strings.NewReader(`# zh_CN
+goroutine = goroutine ! 协程
+slice = 切片 ! 片段 ! 分片
+interface = 接口
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g) != 3 || len(g[1].Forbidden) != 2 || g[1].Target != "切片" {
+		t.Fatalf("ParseGlossary = %+v", g)
+	}
+
+	const src = `package p
+
+// A starts Goroutines.
+
+// A 启动协程.
+func A()
+
+// B returns slices.
+//
+//	var x interface{}
+
+// B 返回切片.
+//
+//	var x interface{}
+func B()
+
+// C returns slices and interfaces.
+// It is not sliced.
+
+// C 返回分片和接口.
+func C()
+
+// D returns a slice.
+func D()
+`
+	records := CheckGlossary(parseGodocu(t, src), g)
+	want := []GlossaryRecord{
+		{"p", "A", TermMissing, "goroutine", "goroutine", ""},
+		{"p", "A", TermForbidden, "goroutine", "goroutine", "协程"},
+		{"p", "C", TermMissing, "slice", "切片", ""},
+		{"p", "C", TermForbidden, "slice", "切片", "分片"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("CheckGlossary = %+v", records)
+	}
+	for i := range want {
+		if records[i] != want[i] {
+			t.Errorf("CheckGlossary[%d] = %+v, want %+v", i, records[i], want[i])
+		}
+	}
+}
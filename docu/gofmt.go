@@ -0,0 +1,299 @@
+package docu
+
+import (
+	"bytes"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"strings"
+	"text/tabwriter"
+)
+
+// Gofmt 表示 FprintGenDecl, FprintValueSpec, FprintTypeSpec, FprintFieldList
+// 采用 gofmt 的对齐和分段规则, 输出的代码经 gofmt 格式化后保持不变.
+// 缺省的对齐方式参见 README 中的格式化差异.
+var Gofmt bool
+
+var gofmtConfig = printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+
+// gofmtExpr 返回已转义的 gofmt 风格 expr 代码及其是否为多行.
+// indent 是后续行的 tab 缩进个数. 没有位置信息时无法正确放置字段的注释, 因此不输出.
+func gofmtExpr(expr ast.Expr, indent int) (string, bool) {
+	var fields []*ast.Field
+	var saved []ast.Field
+	ast.Inspect(expr, func(node ast.Node) bool {
+		if f, ok := node.(*ast.Field); ok && (f.Doc != nil || f.Comment != nil) {
+			fields, saved = append(fields, f), append(saved, *f)
+			f.Doc, f.Comment = nil, nil
+		}
+		return true
+	})
+	var buf bytes.Buffer
+	cfg := gofmtConfig
+	cfg.Indent = indent
+	cfg.Fprint(&buf, emptyfset, expr)
+	for i, f := range fields {
+		*f = saved[i]
+	}
+	s := strings.TrimLeft(buf.String(), "\t")
+	return tabEscapes + s + tabEscapes, strings.IndexByte(s, '\n') != -1
+}
+
+// exprLit 返回 expr 的字面值, Gofmt 模式下与 gofmt 一致.
+func exprLit(expr ast.Expr) string {
+	if !Gofmt {
+		return types.ExprString(expr)
+	}
+	s, _ := gofmtExpr(expr, 0)
+	return s[1 : len(s)-1]
+}
+
+// gofmtComment 返回 gofmt 风格的尾注释 comment, 之前是 extraTabs 个 sep.
+// 没有 "\v" 分隔时以 "\t" 分隔. 多行的注释同 gofmt 以 "\f" 换行, 结束对齐.
+func gofmtComment(comments []*ast.CommentGroup, comment *ast.CommentGroup,
+	sep string, extraTabs int) string {
+
+	if comment == nil {
+		return ""
+	}
+	s := trimNL(comments, comment)
+	if s == "" {
+		return ""
+	}
+	s = tabEscapes + strings.Replace(s, nl, tabEscapes+"\f"+tabEscapes, -1) + tabEscapes
+	if extraTabs == 0 || sep != "\v" {
+		return "\t" + s
+	}
+	return strings.Repeat(sep, extraTabs) + s
+}
+
+// keepTypeColumn 同 go/printer, 返回分组的 specs 中哪些 ValueSpec 需保留类型列.
+// 具有值的连续 specs 中只要有一个具有类型, 这些 specs 都保留类型列.
+func keepTypeColumn(specs []ast.Spec) []bool {
+	m := make([]bool, len(specs))
+	populate := func(i, j int, keepType bool) {
+		if keepType {
+			for ; i < j; i++ {
+				m[i] = true
+			}
+		}
+	}
+
+	i0 := -1 // 当前连续 specs 的开始
+	var keepType bool
+	for i, s := range specs {
+		t := s.(*ast.ValueSpec)
+		if t.Values != nil {
+			if i0 < 0 {
+				i0 = i
+				keepType = false
+			}
+		} else if i0 >= 0 {
+			populate(i0, i, keepType)
+			i0 = -1
+		}
+		if t.Type != nil {
+			keepType = true
+		}
+	}
+	if i0 >= 0 {
+		populate(i0, len(specs), keepType)
+	}
+	return m
+}
+
+// gofmtGenDecl 以 gofmt 风格向 tw 输出 decl 的 specs, 多行的 spec 之后分段.
+func gofmtGenDecl(tw *tabwriter.Writer, indent int,
+	decl *ast.GenDecl, comments []*ast.CommentGroup) (err error) {
+
+	var specs []ast.Spec
+	for _, spec := range decl.Specs {
+		if spec != nil {
+			specs = append(specs, spec)
+		}
+	}
+	var keepType []bool
+	if len(specs) > 1 && decl.Tok != token.TYPE {
+		keepType = keepTypeColumn(specs)
+	}
+
+	multi := false
+	for i, spec := range specs {
+		if multi {
+			if err = tw.Flush(); err != nil {
+				break
+			}
+		}
+		switch spec := spec.(type) {
+		case *ast.ValueSpec:
+			if i != 0 && spec.Doc != nil {
+				if err = fprint(tw, nl); err != nil {
+					break
+				}
+			}
+			multi, err = gofmtValueSpec(tw, indent, spec,
+				keepType != nil && keepType[i], comments)
+		case *ast.TypeSpec:
+			multi, err = gofmtTypeSpec(tw, indent, spec, len(specs) > 1, comments)
+		}
+		if err != nil {
+			break
+		}
+	}
+	return
+}
+
+// gofmtValueSpec 以 gofmt 风格向 w 输出 vs, 返回 vs 是否输出为多行.
+func gofmtValueSpec(w *tabwriter.Writer, indent int, vs *ast.ValueSpec,
+	keepType bool, comments []*ast.CommentGroup) (multi bool, err error) {
+
+	if err = Format(w, indent, vs.Doc, comments); err != nil {
+		return
+	}
+	text := indents[indent] + IdentsLit(vs.Names)
+	extraTabs := 3
+	if vs.Type != nil || keepType {
+		text += "\v"
+		extraTabs--
+	}
+	if st, ok := vs.Type.(*ast.StructType); ok && len(vs.Values) == 0 &&
+		indent+1 < len(indents) && st.Fields != nil && len(st.Fields.List) != 0 {
+		// 匿名结构体以 gofmtFieldList 输出字段的注释
+		if err = fprint(w, text, "struct {\f"); err == nil {
+			err = gofmtFieldList(w, indent+1, st.Fields, comments)
+		}
+		if err != nil {
+			return
+		}
+		text, multi = indents[indent]+"}", true
+	} else if vs.Type != nil {
+		s, m := gofmtExpr(vs.Type, indent)
+		text, multi = text+s, m
+	}
+	for i, expr := range vs.Values {
+		if i == 0 {
+			text += "\v= "
+			extraTabs--
+		} else {
+			text += ", "
+		}
+		s, m := gofmtExpr(expr, indent)
+		text, multi = text+s, multi || m
+	}
+	text += gofmtComment(comments, vs.Comment, "\v", extraTabs)
+	err = fprint(w, text, nl)
+	return
+}
+
+// gofmtTypeSpec 以 gofmt 风格向 w 输出 ts, 返回 ts 是否输出为多行.
+// grouped 表示 ts 位于多个 spec 的分组声明中.
+func gofmtTypeSpec(w *tabwriter.Writer, indent int, ts *ast.TypeSpec,
+	grouped bool, comments []*ast.CommentGroup) (multi bool, err error) {
+
+	if err = Format(w, indent, ts.Doc, comments); err != nil {
+		return
+	}
+	text := indents[indent] + ts.Name.String() + TypeParamsLit(ts.TypeParams)
+	if grouped {
+		text += "\v"
+	} else {
+		text += " "
+	}
+	if ts.Assign.IsValid() {
+		text += "= "
+	}
+
+	if indent+1 < len(indents) {
+		switch t := ts.Type.(type) {
+		case *ast.StructType:
+			if t.Fields != nil && len(t.Fields.List) != 0 {
+				if err = fprint(w, text, "struct {\f"); err == nil {
+					err = FprintFieldList(w, indent+1, t.Fields, comments)
+				}
+				if err == nil {
+					err = fprint(w, indents[indent], "}\f")
+				}
+				return true, err
+			}
+		case *ast.InterfaceType:
+			if t.Methods != nil && len(t.Methods.List) != 0 {
+				if err = fprint(w, text, "interface {\f"); err == nil {
+					err = FprintMethods(w, indent+1, t.Methods, comments)
+				}
+				if err == nil {
+					err = fprint(w, indents[indent], "}\f")
+				}
+				return true, err
+			}
+		}
+	}
+
+	s, multi := gofmtExpr(ts.Type, indent)
+	err = fprint(w, text, s, gofmtComment(comments, ts.Comment, "\t", 0), nl)
+	return
+}
+
+// gofmtFieldList 以 gofmt 风格向 w 输出结构体的 fields, 嵌套的结构体递归输出.
+func gofmtFieldList(w *tabwriter.Writer, indent int,
+	fields *ast.FieldList, comments []*ast.CommentGroup) (err error) {
+
+	sep := "\v"
+	if len(fields.List) == 1 {
+		sep = " "
+	}
+	multi := false
+	for i, field := range fields.List {
+		if multi {
+			if err = w.Flush(); err != nil {
+				break
+			}
+		}
+		if field.Doc != nil {
+			// 注释前加换行
+			if i != 0 {
+				fprint(w, nl)
+			}
+			if err = Format(w, indent, field.Doc, comments); err != nil {
+				break
+			}
+		}
+
+		text := indents[indent]
+		extraTabs := 2
+		if len(field.Names) != 0 {
+			text += IdentsLit(field.Names) + sep
+			extraTabs = 1
+		}
+		st, ok := field.Type.(*ast.StructType)
+		if ok && indent+1 < len(indents) &&
+			st.Fields != nil && len(st.Fields.List) != 0 {
+			if err = fprint(w, text, "struct {\f"); err == nil {
+				err = gofmtFieldList(w, indent+1, st.Fields, comments)
+			}
+			if err != nil {
+				break
+			}
+			text, multi = indents[indent]+"}", true
+		} else {
+			s, m := gofmtExpr(field.Type, indent)
+			text, multi = text+s, m
+		}
+
+		if field.Tag != nil {
+			if len(field.Names) != 0 && sep == "\v" {
+				text += sep
+			}
+			text += sep + tabEscapes + field.Tag.Value + tabEscapes
+			extraTabs = 0
+		}
+		text += gofmtComment(comments, field.Comment, sep, extraTabs)
+		if err = fprint(w, text, nl); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	return
+}
@@ -0,0 +1,135 @@
+package docu
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// codeLines 返回 src 中的非注释行, gofmt 会重排文档中的代码块缩进.
+func codeLines(src []byte) []string {
+	var lines []string
+	for _, line := range strings.Split(string(src), "\n") {
+		if !strings.HasPrefix(strings.TrimLeft(line, "\t"), "//") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// checkGofmt 以 Gofmt 模式输出 file, 返回 gofmt 格式化前后不同的首行.
+func checkGofmt(t *testing.T, file *ast.File) (string, string) {
+	Gofmt = true
+	defer func() { Gofmt = false }()
+
+	var buf bytes.Buffer
+	if err := Fprint(&buf, file); err != nil {
+		t.Fatal(err)
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		t.Fatalf("%v\n%s", err, buf.Bytes())
+	}
+	have, want := codeLines(buf.Bytes()), codeLines(src)
+	for i := range have {
+		if i >= len(want) || have[i] != want[i] {
+			if i < len(want) {
+				return have[i], want[i]
+			}
+			return have[i], ""
+		}
+	}
+	if len(want) > len(have) {
+		return "", want[len(have)]
+	}
+	return "", ""
+}
+
+func TestGofmt(t *testing.T) {
+	const src = `package p
+
+import "io"
+
+const Docu = 1 // comment Docu
+const Hi = 1   // comment Hi
+
+const (
+	A     = iota // a
+	Bbbbb        // b
+
+	C int = 1
+	D     = "d" // d
+	E, F  = 1, 2
+)
+
+var V struct {
+	io.Reader // embedded
+	// N is n.
+	N, Nnnn int ` + "`json:\"n\"`" + `
+	Sub     struct {
+		X   int // x
+		Yyy string
+	} ` + "`json:\"sub\"`" + `
+	Z []struct{ A, B int }
+}
+
+type (
+	T1   int
+	Tttt = string
+	I    interface {
+		M() // m
+	}
+	S struct{}
+)
+`
+	file, err := parser.ParseFile(token.NewFileSet(), "p.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Index(file)
+	if have, want := checkGofmt(t, file); have != want {
+		t.Errorf("Gofmt\nhave %q\nwant %q", have, want)
+	}
+}
+
+func TestGofmtGOROOT(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping GOROOT round trip in short mode")
+	}
+	root := filepath.Join(GOROOT, "src")
+	n := 0
+	filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.IsDir() {
+			return nil
+		}
+		if name := fi.Name(); name == "testdata" || name[0] == '.' || name[0] == '_' {
+			return filepath.SkipDir
+		}
+		du := New()
+		key, err := du.Parse(path, nil)
+		if err != nil || key == "" {
+			return nil
+		}
+		file := du.MergePackageFiles(key)
+		if file == nil {
+			return nil
+		}
+		file.Unresolved = nil
+		ExportedFileFilter(file)
+		Index(file)
+		if have, want := checkGofmt(t, file); have != want {
+			t.Errorf("%s:\nhave %q\nwant %q", key, have, want)
+		}
+		n++
+		return nil
+	})
+	if n == 0 {
+		t.Skip("no GOROOT packages")
+	}
+}
@@ -0,0 +1,249 @@
+package docu
+
+import (
+	"bytes"
+	"go/ast"
+	"go/doc"
+	"go/scanner"
+	"go/token"
+	"html/template"
+	"strings"
+)
+
+// Anchor 返回 decl 在 HTML 文档中的锚点名称, 方法形如 "Type.Method".
+func Anchor(decl ast.Decl) string {
+	lit := DeclIdentLit(decl)
+	if lit != "" && lit[0] == '*' {
+		return lit[1:]
+	}
+	return lit
+}
+
+// Anchors 返回 file 中顶级声明的标识符集合, 用于 HTMLCode 链接标识符.
+func Anchors(file *ast.File) map[string]bool {
+	links := make(map[string]bool)
+	for _, decl := range file.Decls {
+		switch n := decl.(type) {
+		case *ast.GenDecl:
+			for _, name := range SpecNames(n) {
+				links[name] = true
+			}
+		case *ast.FuncDecl:
+			if n.Recv == nil {
+				links[n.Name.Name] = true
+			}
+		}
+	}
+	return links
+}
+
+// SpecNames 返回 decl 中常量, 变量, 类型声明的标识符, 忽略 "_".
+func SpecNames(decl ast.Decl) (names []string) {
+	genDecl, ok := decl.(*ast.GenDecl)
+	if !ok {
+		return
+	}
+	for _, spec := range genDecl.Specs {
+		switch n := spec.(type) {
+		case *ast.ValueSpec:
+			for _, ident := range n.Names {
+				if ident.Name != "_" {
+					names = append(names, ident.Name)
+				}
+			}
+		case *ast.TypeSpec:
+			names = append(names, n.Name.Name)
+		}
+	}
+	return
+}
+
+// HTMLCode 返回 Go 代码 code 的语法高亮 HTML, 不含外层 pre 标签.
+// 关键字, 注释, 字符串, 数值分别使用 class "kw", "com", "str", "num".
+// 属于 links 的标识符链接到同名锚点, 选择器 "x.Name" 中的 Name 除外.
+func HTMLCode(code string, links map[string]bool) string {
+	var buf bytes.Buffer
+	var s scanner.Scanner
+	src := []byte(code)
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	s.Init(file, src, nil, scanner.ScanComments)
+
+	pos, prev := 0, token.ILLEGAL
+	for {
+		p, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+		offset := file.Offset(p)
+		text := lit
+		if text == "" {
+			text = tok.String()
+		}
+		if offset < pos || offset+len(text) > len(code) {
+			continue
+		}
+		template.HTMLEscape(&buf, src[pos:offset])
+		pos = offset + len(text)
+
+		class := ""
+		switch {
+		case tok.IsKeyword():
+			class = "kw"
+		case tok == token.COMMENT:
+			class = "com"
+		case tok == token.STRING || tok == token.CHAR:
+			class = "str"
+		case tok == token.INT || tok == token.FLOAT || tok == token.IMAG:
+			class = "num"
+		case tok == token.IDENT && prev != token.PERIOD && links[text]:
+			buf.WriteString(`<a href="#` + text + `">` + text + `</a>`)
+			prev = tok
+			continue
+		}
+		if tok != token.COMMENT {
+			prev = tok
+		}
+		if class != "" {
+			buf.WriteString(`<span class="` + class + `">`)
+		}
+		template.HTMLEscape(&buf, src[offset:pos])
+		if class != "" {
+			buf.WriteString("</span>")
+		}
+	}
+	template.HTMLEscape(&buf, src[pos:])
+	return buf.String()
+}
+
+// HTMLDoc 利用 doc.ToHTML 返回文档 text 的 HTML.
+// 双语文档以 SplitComments 分割, 原文 class 为 "doc origin", 译文 class 为 "doc".
+// 多语言文档输出 Langs 选中的全部译文, 带 lang 属性.
+func HTMLDoc(text string) string {
+	var buf bytes.Buffer
+	if HasLangs(text) {
+		origin, trans := SplitTranslations(text)
+		if origin != "" {
+			buf.WriteString(`<div class="doc origin">` + nl)
+			doc.ToHTML(&buf, origin, nil)
+			buf.WriteString("</div>" + nl)
+		}
+		for _, t := range SelectTranslations(trans, Langs) {
+			if t.Lang == "" {
+				buf.WriteString(`<div class="doc">` + nl)
+			} else {
+				buf.WriteString(`<div class="doc" lang="` + t.Lang + `">` + nl)
+			}
+			doc.ToHTML(&buf, t.Text, nil)
+			buf.WriteString("</div>" + nl)
+		}
+		return buf.String()
+	}
+	origin, trans := SplitComments(text)
+	if origin != "" && strings.TrimSpace(origin) != strings.TrimSpace(trans) {
+		buf.WriteString(`<div class="doc origin">` + nl)
+		doc.ToHTML(&buf, origin, nil)
+		buf.WriteString("</div>" + nl)
+	}
+	if strings.TrimSpace(trans) != "" {
+		buf.WriteString(`<div class="doc">` + nl)
+		doc.ToHTML(&buf, trans, nil)
+		buf.WriteString("</div>" + nl)
+	}
+	return buf.String()
+}
+
+// HTMLTemplate 为内置的 HTML 模板, 依赖 FuncsMap 中的 html 系列函数.
+const HTMLTemplate = `{{define "code"}}<pre class="code">{{.}}</pre>
+{{end}}{{define "example"}}<details class="example">
+<summary>Example{{if $s := exampleSuffix .}} ({{html $s}}){{end}}</summary>
+{{htmlDoc .Doc.Text}}{{template "code" html (exampleCode .)}}</details>
+{{end}}{{/*
+此模板输出 godoc 风格的 HTML 页面.
+每个声明具有锚点, 方法形如 "#Type.Method", 代码中的包级标识符链接到对应锚点.
+常量, 变量分组声明中的每个标识符都具有锚点.
+双语文档的原文和译文分别输出, 原文 class 为 "doc origin".
+各部分以 block 定义, 执行数据都是 Data, 可用 define 单独覆盖:
+header, style, index, consts, vars, funcs, types, notes, footer.
+示例函数以 example 输出, 方法 ExamplesOf 返回属于某个声明的示例.
+*/}}{{if eq .Key .ImportPath}}{{$.Type "html"}}{{block "header" .}}{{$this := .File}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{base .ImportPath | html}} - Go</title>
+{{block "style" .}}<style>
+body { font-family: sans-serif; margin: 0 auto; max-width: 960px; padding: 0 20px; line-height: 1.5; }
+pre { background: #f5f5f5; padding: 10px; overflow-x: auto; line-height: 1.4; }
+a { color: #375eab; text-decoration: none; }
+h2 a.permalink, h3 a.permalink { visibility: hidden; margin-left: 4px; }
+h2:hover a.permalink, h3:hover a.permalink { visibility: visible; }
+.kw { color: #00f; }
+.com { color: #080; }
+.str { color: #a31515; }
+.num { color: #098658; }
+.doc.origin { color: #666; border-left: 3px solid #ddd; padding-left: 10px; }
+#index dd { margin: 0 0 0 20px; }
+details.example { margin: 10px 0; }
+details.example summary { cursor: pointer; color: #375eab; }
+</style>
+{{end}}</head>
+<body>
+<h1>Package {{base .ImportPath | html}}</h1>
+<pre class="code"><span class="kw">import</span> <span class="str">"{{html .ImportPath}}"</span></pre>
+{{if $x := canonicalImportPaths $this}}<pre class="code">{{html $x}}</pre>
+{{end}}{{if $trans := progress $this}}<p>Translation Progress: {{$trans}}%</p>
+{{end}}<h2 id="pkg-overview">Overview</h2>
+{{if $this.Doc}}{{htmlDoc $this.Doc.Text}}{{end}}{{/*
+*/}}{{range .Normal.ExamplesOf ""}}{{template "example" .}}{{end}}{{end}}{{/*
+
+索引
+*/}}{{block "index" .}}{{$g := .Normal}}<h2 id="pkg-index">Index</h2>
+<dl id="index">
+{{if $g.Consts}}<dd><a href="#pkg-constants">Constants</a></dd>
+{{end}}{{if $g.Vars}}<dd><a href="#pkg-variables">Variables</a></dd>
+{{end}}{{range $x := $g.Funcs}}<dd><a href="#{{anchor $x}}">{{$.Code $x | html}}</a></dd>
+{{end}}{{range $t := $g.Types}}<dd><a href="#{{$t.Name}}">type {{$t.Name}}</a></dd>
+{{range $x := $t.Funcs}}<dd><dl><dd><a href="#{{anchor $x}}">{{$.Code $x | html}}</a></dd></dl></dd>
+{{end}}{{range $x := $t.Methods}}<dd><dl><dd><a href="#{{anchor $x}}">{{$.Code $x | html}}</a></dd></dl></dd>
+{{end}}{{end}}{{range $marker, $_ := .Package.Notes}}<dd><a href="#pkg-note-{{$marker}}">{{noteTitle $marker}}s</a></dd>
+{{end}}</dl>
+{{end}}{{/*
+
+常量, 变量
+*/}}{{block "consts" .}}{{$links := anchors .File}}{{$g := .Normal}}{{if $g.Consts}}<h2 id="pkg-constants">Constants</h2>
+{{range $x := $g.Consts}}{{range specNames $x}}<span id="{{.}}"></span>{{end}}{{template "code" htmlCode ($.Code $x) $links}}{{htmlDoc ($.Text $x)}}{{end}}{{end}}{{end}}{{/*
+*/}}{{block "vars" .}}{{$links := anchors .File}}{{$g := .Normal}}{{if $g.Vars}}<h2 id="pkg-variables">Variables</h2>
+{{range $x := $g.Vars}}{{range specNames $x}}<span id="{{.}}"></span>{{end}}{{template "code" htmlCode ($.Code $x) $links}}{{htmlDoc ($.Text $x)}}{{end}}{{end}}{{end}}{{/*
+
+函数
+*/}}{{block "funcs" .}}{{$links := anchors .File}}{{$g := .Normal}}{{range $x := $g.Funcs}}<h2 id="{{anchor $x}}">func {{identLit $x}} <a class="permalink" href="#{{anchor $x}}">&para;</a></h2>
+{{template "code" htmlCode ($.Code $x) $links}}{{htmlDoc ($.Text $x)}}{{/*
+*/}}{{range $g.ExamplesOf (anchor $x)}}{{template "example" .}}{{end}}{{end}}{{end}}{{/*
+
+类型
+*/}}{{block "types" .}}{{$links := anchors .File}}{{$g := .Normal}}{{range $t := $g.Types}}<h2 id="{{$t.Name}}">type {{$t.Name}} <a class="permalink" href="#{{$t.Name}}">&para;</a></h2>
+{{template "code" htmlCode ($.Code $t.Decl) $links}}{{htmlDoc ($.Text $t.Decl)}}{{/*
+*/}}{{range $g.ExamplesOf $t.Name}}{{template "example" .}}{{end}}{{/*
+类型常量, 变量, 构造函数
+*/}}{{range $x := $t.Consts}}{{range specNames $x}}<span id="{{.}}"></span>{{end}}{{template "code" htmlCode ($.Code $x) $links}}{{htmlDoc ($.Text $x)}}{{end}}{{/*
+*/}}{{range $x := $t.Vars}}{{range specNames $x}}<span id="{{.}}"></span>{{end}}{{template "code" htmlCode ($.Code $x) $links}}{{htmlDoc ($.Text $x)}}{{end}}{{/*
+*/}}{{range $x := $t.Funcs}}<h3 id="{{anchor $x}}">func {{identLit $x}} <a class="permalink" href="#{{anchor $x}}">&para;</a></h3>
+{{template "code" htmlCode ($.Code $x) $links}}{{htmlDoc ($.Text $x)}}{{/*
+*/}}{{range $g.ExamplesOf (anchor $x)}}{{template "example" .}}{{end}}{{end}}{{/*
+成员方法
+*/}}{{range $m := $t.Methods}}<h3 id="{{anchor $m}}">func ({{recvIdentLit $m}}) {{$m.Name.Name}} <a class="permalink" href="#{{anchor $m}}">&para;</a></h3>
+{{template "code" htmlCode ($.Code $m) $links}}{{htmlDoc ($.Text $m)}}{{/*
+*/}}{{range $g.ExamplesOf (anchor $m)}}{{template "example" .}}{{end}}{{end}}{{end}}{{end}}{{/*
+*/}}{{block "notes" .}}{{range $marker, $notes := .Package.Notes}}<h2 id="pkg-note-{{$marker}}">{{noteTitle $marker}}s</h2>
+<ul>
+{{range $notes}}<li>&#x261e; {{htmlDoc .Body.Text}}</li>
+{{end}}</ul>
+{{end}}{{end}}{{/*
+*/}}{{block "footer" .}}{{if $x := license .File}}<h2 id="pkg-license">License</h2>
+<pre>{{html $x}}</pre>
+{{end}}</body>
+</html>
+{{end}}{{end}}`
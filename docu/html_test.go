@@ -0,0 +1,39 @@
+package docu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHTMLCode(t *testing.T) {
+	links := map[string]bool{"List": true, "New": true}
+	code := "func New(s string) *List // a < b\n"
+	want := `<span class="kw">func</span> <a href="#New">New</a>(s string) *<a href="#List">List</a>` +
+		` <span class="com">// a &lt; b</span>` + "\n"
+	if got := HTMLCode(code, links); got != want {
+		t.Errorf("HTMLCode =\n%s\nwant\n%s", got, want)
+	}
+
+	// 选择器中的标识符不链接
+	code = `var x = list.List{} + "New"`
+	want = `<span class="kw">var</span> x = list.List{} + <span class="str">&#34;New&#34;</span>`
+	if got := HTMLCode(code, links); got != want {
+		t.Errorf("HTMLCode =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHTMLDoc(t *testing.T) {
+	got := HTMLDoc("Open opens.\n" + GoDocu_Dividing_line + "\nOpen 打开.\n")
+	origin := strings.Index(got, `<div class="doc origin">`)
+	trans := strings.Index(got, `<div class="doc">`)
+	if origin == -1 || trans < origin ||
+		!strings.Contains(got[origin:trans], "Open opens.") ||
+		!strings.Contains(got[trans:], "Open 打开.") {
+		t.Errorf("HTMLDoc bilingual =\n%s", got)
+	}
+
+	got = HTMLDoc("a < b\n")
+	if strings.Contains(got, "origin") || !strings.Contains(got, "a &lt; b") {
+		t.Errorf("HTMLDoc =\n%s", got)
+	}
+}
@@ -15,7 +15,8 @@ const (
 	TypeNum
 	FuncNum
 	MethodNum
-	OtherNum = 1 << 32
+	ExampleNum // 示例函数, 参见 isTextExample
+	OtherNum   = 1 << 32
 )
 
 var numNames = []string{
@@ -44,6 +45,9 @@ func NodeNumber(node ast.Node) int {
 			return TypeNum
 		}
 	case *ast.FuncDecl:
+		if isTextExample(n) {
+			return ExampleNum
+		}
 		if n.Recv == nil {
 			return FuncNum
 		}
@@ -73,12 +77,22 @@ func (s SortDecl) Less(i, j int) bool {
 			break
 		}
 		return SpecIdentLit(si[0]) < SpecIdentLit(sj[0])
-	case FuncNum, MethodNum:
-		return FuncLit(s[i].(*ast.FuncDecl)) < FuncLit(s[j].(*ast.FuncDecl))
+	case FuncNum, MethodNum, ExampleNum:
+		return funcLess(s[i].(*ast.FuncDecl), s[j].(*ast.FuncDecl))
 	}
 	return false
 }
 
+// funcLess 按 FuncIdentLit 比较函数声明, 相同时再比较 FuncLit.
+// 泛型方法的接收者类型参数名称不影响次序.
+func funcLess(a, b *ast.FuncDecl) bool {
+	al, bl := FuncIdentLit(a), FuncIdentLit(b)
+	if al != bl {
+		return al < bl
+	}
+	return FuncLit(a) < FuncLit(b)
+}
+
 // Search 查找 identLit 所在的顶级声明.
 func (s SortDecl) Search(identLit string) ast.Decl {
 	if identLit == "" || identLit == "<nil>" {
@@ -132,6 +146,7 @@ func (s SortDecl) SearchFunc(funcIdentLit string) *ast.FuncDecl {
 // 	*typeLit, error
 // 	typeLit
 // 	typeLit, error
+// 泛型类型的类型实参不参与匹配, 比如 *typeLit[T].
 func (s SortDecl) SearchConstructor(typeLit string) *ast.FuncDecl {
 	if typeLit == "" || typeLit == "<nil>" {
 		return nil
@@ -151,8 +166,15 @@ func (s SortDecl) SearchConstructor(typeLit string) *ast.FuncDecl {
 }
 
 func isConstructor(n *ast.FuncDecl, typeLit string) bool {
-	if !n.Name.IsExported() || n.Type.Results == nil {
-		return false
+	lit := constructorTypeLit(n)
+	return lit != "" && lit == typeLit
+}
+
+// constructorTypeLit 返回构造函数 n 所构造的类型名, 不含星号.
+// 如果 n 不是构造函数返回 "".
+func constructorTypeLit(n *ast.FuncDecl) string {
+	if n.Recv != nil || !n.Name.IsExported() || n.Type.Results == nil {
+		return ""
 	}
 	list := n.Type.Results.List
 	switch len(list) {
@@ -162,12 +184,32 @@ func isConstructor(n *ast.FuncDecl, typeLit string) bool {
 		}
 		fallthrough
 	case 1:
-		lit := types.ExprString(list[0].Type)
-		if lit == typeLit || lit[0] == '*' && lit[1:] == typeLit {
-			return true
+		if len(list[0].Names) > 1 {
+			break
+		}
+		lit := typeIdentLit(list[0].Type)
+		if lit != "" && lit[0] == '*' {
+			lit = lit[1:]
 		}
+		return lit
 	}
-	return false
+	return ""
+}
+
+// typeIdentLit 返回类型 expr 的字面描述, 剔除泛型类型实参.
+func typeIdentLit(expr ast.Expr) string {
+	switch n := expr.(type) {
+	case *ast.StarExpr:
+		if lit := typeIdentLit(n.X); lit != "" {
+			return "*" + lit
+		}
+		return ""
+	case *ast.IndexExpr:
+		return types.ExprString(n.X)
+	case *ast.IndexListExpr:
+		return types.ExprString(n.X)
+	}
+	return types.ExprString(expr)
 }
 
 // SearchSpec 查找 specIdentLit 对应的顶级 ast.Spec 和所在 *ast.GenDecl 以及索引.
@@ -207,7 +249,7 @@ func (s SortImports) Less(i, j int) bool {
 
 // Index 剔除 file.Decls 中的 import 声明, 并对顶级声明重新排序. 按照:
 //
-//	Consts, Vars, Types, Funcs, Method
+//	Consts, Vars, Types, Funcs, Method, Examples
 func Index(file *ast.File) {
 	if file != nil {
 		clearFile(file)
@@ -242,52 +284,219 @@ func declsOf(num int, decls []ast.Decl, offset int) ([]ast.Decl, int) {
 	return decls[first:last], last
 }
 
-// IndexNormal 对 file 顶级声明进行常规习惯排序, 即:
+// IndexNormal 对 file 顶级声明进行常规习惯排序, 即 godoc 风格:
 //
-//	Consts, Vars, Funcs, Types [Constructor,Method]
+//	Consts, Vars, Funcs, Types [Consts, Vars, Constructors, Methods]
 //
+// 同组内保持 Index 的次序.
 func IndexNormal(file *ast.File) {
-	panic("Unimplemented")
-	sort.Sort(sortNormal(file.Decls))
+	if file != nil {
+		Index(file)
+		file.Decls = GroupNormal(file.Decls).Decls()
+	}
+}
+
+// IndexSource 剔除 file.Decls 中的 import 声明, 并对顶级声明按源码位置排序.
+// 多文件合并的包按文件名次序排列.
+func IndexSource(file *ast.File) {
+	if file != nil {
+		Index(file)
+		sort.Stable(sortSource(file.Decls))
+	}
 }
 
-var normalOrder = [...]int{
-	ImportNum,
-	ConstNum,
-	VarNum,
-	FuncNum,
-	TypeNum,
-	MethodNum,
+// sortSource 实现 sort.Interface. 按源码位置排序.
+type sortSource []ast.Decl
+
+func (s sortSource) Len() int           { return len(s) }
+func (s sortSource) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s sortSource) Less(i, j int) bool { return s[i].Pos() < s[j].Pos() }
+
+// Orders 为可选的顶级声明排序方式及其排序函数.
+var Orders = map[string]func(*ast.File){
+	"index":  Index,
+	"normal": IndexNormal,
+	"source": IndexSource,
 }
 
-// sortNormal 实现 sort.Interface. 按常规习惯排序.
-type sortNormal []ast.Decl
+// Normal 表示按常规习惯分组的顶级声明.
+type Normal struct {
+	Consts []ast.Decl // 未归属类型的常量
+	Vars   []ast.Decl // 未归属类型的变量
+	Funcs  []ast.Decl // 非构造函数, 以及类型不在 decls 中的方法
+	Types  []*NormalType
+	// Examples 为全部示例函数, 参见 ExamplesOf.
+	Examples []*ast.FuncDecl
+}
 
-func (s sortNormal) Len() int      { return len(s) }
-func (s sortNormal) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
-func (s sortNormal) Less(i, j int) bool {
-	in, jn := NodeNumber(s[i]), NodeNumber(s[j])
-	if in == OtherNum || jn == OtherNum ||
-		in != jn && (in <= VarNum || jn <= VarNum) {
-		return in < jn
+// ExamplesOf 返回 n 中属于标识符 ident 的示例函数, ident 同 Anchor, 包为 "".
+func (n *Normal) ExamplesOf(ident string) (examples []*ast.FuncDecl) {
+	for _, fn := range n.Examples {
+		if ExampleIdent(fn) == ident {
+			examples = append(examples, fn)
+		}
 	}
+	return
+}
+
+// NormalType 表示类型声明及归属该类型的常量, 变量, 构造函数和方法.
+type NormalType struct {
+	Decl    *ast.GenDecl // 类型声明, 可能是分组声明
+	Consts  []ast.Decl
+	Vars    []ast.Decl
+	Funcs   []ast.Decl // 构造函数
+	Methods []ast.Decl
+}
 
-	if in != jn && (in == FuncNum || jn == FuncNum) {
-		return normalOrder[in] < normalOrder[jn]
+// Name 返回 t 首个类型的名称.
+func (t *NormalType) Name() string {
+	return DeclIdentLit(t.Decl)
+}
+
+// Decls 按 Consts, Vars, Funcs, Types, Examples 次序返回 n 中的全部声明.
+func (n *Normal) Decls() []ast.Decl {
+	decls := make([]ast.Decl, 0, len(n.Consts)+len(n.Vars)+len(n.Funcs)+len(n.Types))
+	decls = append(decls, n.Consts...)
+	decls = append(decls, n.Vars...)
+	decls = append(decls, n.Funcs...)
+	for _, t := range n.Types {
+		decls = append(decls, t.Decl)
+		decls = append(decls, t.Consts...)
+		decls = append(decls, t.Vars...)
+		decls = append(decls, t.Funcs...)
+		decls = append(decls, t.Methods...)
 	}
-	// TypeNum, MethodNum
-	if in == jn {
-		switch in {
-		case TypeNum:
-			si := s[i].(*ast.GenDecl).Specs
-			sj := s[j].(*ast.GenDecl).Specs
-			if len(si) == 0 || len(sj) == 0 {
-				break
+	for _, fn := range n.Examples {
+		decls = append(decls, fn)
+	}
+	return decls
+}
+
+// GroupNormal 按 godoc 习惯对 decls 分组, 同组内保持 decls 中的次序.
+// 细节:
+//	所有具名类型一致的常量, 变量分组声明归属该类型
+//	返回值为 T, *T 或者 (T, error), (*T, error) 的函数是 T 的构造函数
+//	方法归属接收者类型
+//	示例函数单独存放, 参见 ExamplesOf
+//	忽略 import 声明, 不改变 decls
+func GroupNormal(decls []ast.Decl) *Normal {
+	n := new(Normal)
+	types := make(map[string]*NormalType)
+	for _, node := range decls {
+		decl, ok := node.(*ast.GenDecl)
+		if !ok || decl.Tok != token.TYPE {
+			continue
+		}
+		t := &NormalType{Decl: decl}
+		for _, spec := range decl.Specs {
+			types[SpecIdentLit(spec)] = t
+		}
+		n.Types = append(n.Types, t)
+	}
+
+	for _, node := range decls {
+		switch decl := node.(type) {
+		case *ast.GenDecl:
+			switch decl.Tok {
+			case token.CONST:
+				if t := types[valueTypeName(decl)]; t != nil {
+					t.Consts = append(t.Consts, decl)
+				} else {
+					n.Consts = append(n.Consts, decl)
+				}
+			case token.VAR:
+				if t := types[valueTypeName(decl)]; t != nil {
+					t.Vars = append(t.Vars, decl)
+				} else {
+					n.Vars = append(n.Vars, decl)
+				}
+			}
+		case *ast.FuncDecl:
+			if isTextExample(decl) {
+				n.Examples = append(n.Examples, decl)
+				continue
 			}
-			return SpecIdentLit(si[0]) < SpecIdentLit(sj[0])
-		case MethodNum:
-			return FuncLit(s[i].(*ast.FuncDecl)) < FuncLit(s[j].(*ast.FuncDecl))
+			if decl.Recv != nil {
+				lit := RecvIdentLit(decl)
+				if lit != "" && lit[0] == '*' {
+					lit = lit[1:]
+				}
+				if t := types[lit]; t != nil {
+					t.Methods = append(t.Methods, decl)
+					continue
+				}
+			} else if t := types[constructorTypeLit(decl)]; t != nil {
+				t.Funcs = append(t.Funcs, decl)
+				continue
+			}
+			n.Funcs = append(n.Funcs, decl)
 		}
 	}
-	return false
+	return n
+}
+
+// valueTypeName 返回 const, var 声明 decl 归属的类型名.
+// 要求各 spec 的具名类型一致, 且类型是本包声明的.
+func valueTypeName(decl *ast.GenDecl) (name string) {
+	prev := ""
+	for _, spec := range decl.Specs {
+		vs, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			return ""
+		}
+		lit := ""
+		switch {
+		case vs.Type != nil:
+			lit = baseTypeName(vs.Type)
+		case decl.Tok == token.CONST && len(vs.Values) == 0:
+			// iota 延续
+			lit = prev
+		case decl.Tok == token.VAR && len(vs.Values) == 1:
+			lit = compositeTypeName(vs.Values[0])
+		}
+		if lit == "" {
+			// 允许 "_ = iota" 这样的首个无类型常量
+			if prev == "" && name == "" && decl.Tok == token.CONST &&
+				len(vs.Names) == 1 && vs.Names[0].Name == "_" {
+				continue
+			}
+			return ""
+		}
+		if name == "" {
+			name = lit
+		} else if name != lit {
+			return ""
+		}
+		prev = lit
+	}
+	return
+}
+
+// baseTypeName 返回类型 expr 的基础类型名, 剔除指针和泛型类型实参.
+// 外部包类型返回 "".
+func baseTypeName(expr ast.Expr) string {
+	switch n := expr.(type) {
+	case *ast.Ident:
+		return n.Name
+	case *ast.StarExpr:
+		return baseTypeName(n.X)
+	case *ast.ParenExpr:
+		return baseTypeName(n.X)
+	case *ast.IndexExpr:
+		return baseTypeName(n.X)
+	case *ast.IndexListExpr:
+		return baseTypeName(n.X)
+	}
+	return ""
+}
+
+// compositeTypeName 返回复合字面值 T{}, &T{} 的类型名.
+func compositeTypeName(expr ast.Expr) string {
+	if n, ok := expr.(*ast.UnaryExpr); ok && n.Op == token.AND {
+		expr = n.X
+	}
+	if n, ok := expr.(*ast.CompositeLit); ok && n.Type != nil {
+		return baseTypeName(n.Type)
+	}
+	return ""
 }
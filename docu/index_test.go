@@ -0,0 +1,73 @@
+package docu
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+const testNormalSource = `package normal
+
+import "errors"
+
+const Max = 10
+
+type Mode int
+
+const (
+	_ Mode = iota
+	ModeA
+	ModeB
+)
+
+var ErrMode = errors.New("mode")
+
+var DefaultOption = &Option{}
+
+type Option struct{}
+
+func Open() error
+
+func NewOption() (*Option, error)
+
+func (m Mode) String() string
+
+func (o *Option) Set(m Mode)
+`
+
+func TestIndexNormal(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "normal.go", testNormalSource, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	IndexNormal(file)
+
+	want := []string{
+		"Max", "ErrMode", "Open",
+		"Mode", "_", "Mode.String",
+		"Option", "DefaultOption", "NewOption", "*Option.Set",
+	}
+	if len(file.Decls) != len(want) {
+		t.Fatalf("IndexNormal got %d decls, want %d", len(file.Decls), len(want))
+	}
+	for i, decl := range file.Decls {
+		if lit := DeclIdentLit(decl); lit != want[i] {
+			t.Errorf("IndexNormal decls[%d] = %q, want %q", i, lit, want[i])
+		}
+	}
+
+	n := GroupNormal(file.Decls)
+	if len(n.Types) != 2 || n.Types[1].Name() != "Option" ||
+		len(n.Types[1].Vars) != 1 || len(n.Types[1].Funcs) != 1 {
+		t.Fatalf("GroupNormal = %+v", n)
+	}
+
+	IndexSource(file)
+	want = []string{"Max", "Mode", "_", "ErrMode", "DefaultOption", "Option",
+		"Open", "NewOption", "Mode.String", "*Option.Set"}
+	for i, decl := range file.Decls {
+		if lit := DeclIdentLit(decl); lit != want[i] {
+			t.Errorf("IndexSource decls[%d] = %q, want %q", i, lit, want[i])
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package docu
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// LangMul 为多语言文档的 lang, 文件名为 doc_mul.go.
+// 多语言文档中原文之后跟随多个以带 lang 的 GoDocu_Dividing_line 分隔的译文:
+//
+//	// Println formats ...
+//	//
+//	// ___GoDocu_Dividing_line___ zh_CN
+//	//
+//	// Println 格式化 ...
+//	//
+//	// ___GoDocu_Dividing_line___ zh_TW
+//	//
+//	// Println 格式化 ...
+//	func Println(a ...interface{}) (n int, err error)
+const LangMul = "mul"
+
+// Langs 为输出多语言文档时选用的 lang, 按优先次序. 为空时选用全部译文.
+var Langs []string
+
+// Translation 表示多语言文档中的一个译文.
+type Translation struct {
+	Lang string // 译文的 lang, 不带 lang 的分割线之后为空
+	Text string
+}
+
+// dividingLang 返回分割线 line 所带的 lang, ok 表示 line 是否为分割线.
+func dividingLang(line string) (lang string, ok bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, GoDocu_Dividing_line) {
+		return "", false
+	}
+	lang = strings.TrimSpace(line[len(GoDocu_Dividing_line):])
+	if lang != "" && LangNormal(lang) == "" {
+		return "", false
+	}
+	return lang, true
+}
+
+// selectedLang 返回多语言文档 text 中 SplitComments 选用的译文的 lang.
+// 没有选中的译文时返回 Langs 的首个 lang, Langs 为空时 ok 为 false.
+func selectedLang(text string) (lang string, ok bool) {
+	_, trans := SplitTranslations(text)
+	if trans = SelectTranslations(trans, Langs); len(trans) != 0 {
+		return trans[0].Lang, true
+	}
+	if len(Langs) != 0 {
+		return Langs[0], true
+	}
+	return "", false
+}
+
+// translationStart 返回 doc.List 中 SplitComments 选用的译文的起始下标, 即分割线之后.
+// 没有分割线时返回 0, 多语言文档中没有选用的译文时 ok 为 false.
+func translationStart(doc *ast.CommentGroup) (i int, ok bool) {
+	text := doc.Text()
+	if !HasLangs(text) {
+		for k, c := range doc.List {
+			if isDividingLine(c) {
+				return k + 1, true
+			}
+		}
+		return 0, true
+	}
+	lang, ok := selectedLang(text)
+	if !ok {
+		return 0, false
+	}
+	for k, c := range doc.List {
+		if l, ok := dividingLang(strings.TrimPrefix(c.Text, "//")); ok && l == lang {
+			return k + 1, true
+		}
+	}
+	return 0, false
+}
+
+// HasLangs 返回 text 是否为多语言文档, 即含有带 lang 的分割线.
+func HasLangs(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		if lang, ok := dividingLang(line); ok && lang != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitTranslations 以分割线分割 text 为原文和译文, 空译文被忽略.
+// 原文和译文都去除了首尾空行.
+func SplitTranslations(text string) (origin string, trans []Translation) {
+	var (
+		lang  string
+		lines []string
+		first = true
+	)
+	flush := func() {
+		s := trimText(strings.Join(lines, "\n"))
+		if first {
+			origin = s
+		} else if s != "" {
+			trans = append(trans, Translation{lang, s})
+		}
+		lines = lines[:0]
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if l, ok := dividingLang(line); ok {
+			flush()
+			lang, first = l, false
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return
+}
+
+// JoinTranslations 合并原文 origin 和译文 trans 为多语言文档文本.
+func JoinTranslations(origin string, trans []Translation) string {
+	var parts []string
+	if origin = trimText(origin); origin != "" {
+		parts = append(parts, strings.TrimSuffix(origin, "\n"))
+	}
+	for _, t := range trans {
+		line := GoDocu_Dividing_line
+		if t.Lang != "" {
+			line += " " + t.Lang
+		}
+		parts = append(parts, line, strings.TrimSuffix(trimText(t.Text), "\n"))
+	}
+	return strings.Join(parts, "\n\n") + "\n"
+}
+
+// SelectTranslations 返回 trans 中 lang 属于 langs 的译文, 按 langs 的次序.
+// langs 为空时返回 trans. 不带 lang 的译文总被选中, 位于最后.
+func SelectTranslations(trans []Translation, langs []string) []Translation {
+	if len(langs) == 0 {
+		return trans
+	}
+	var selected []Translation
+	for _, lang := range langs {
+		for _, t := range trans {
+			if t.Lang == lang {
+				selected = append(selected, t)
+				break
+			}
+		}
+	}
+	for _, t := range trans {
+		if t.Lang == "" {
+			selected = append(selected, t)
+		}
+	}
+	return selected
+}
+
+// SelectLangs 返回多语言文档 text 中只保留 langs 选中译文的文本.
+// 只选中一个译文时返回经典的双语文档. text 不是多语言文档时原样返回.
+func SelectLangs(text string, langs []string) string {
+	if !HasLangs(text) {
+		return text
+	}
+	origin, trans := SplitTranslations(text)
+	trans = SelectTranslations(trans, langs)
+	switch len(trans) {
+	case 0:
+		return origin
+	case 1:
+		return JoinTranslations(origin, []Translation{{"", trans[0].Text}})
+	}
+	return JoinTranslations(origin, trans)
+}
+
+// formatLangs 返回 Format 输出多语言文档 text 时的原文和译文.
+// 只选用一个 lang 时译文不带分割线.
+func formatLangs(text string) (string, string) {
+	origin, trans := SplitTranslations(text)
+	trans = SelectTranslations(trans, Langs)
+	switch {
+	case len(trans) == 0:
+		return "", origin
+	case len(Langs) == 1:
+		return origin, trans[0].Text
+	}
+	return origin, JoinTranslations("", trans)
+}
+
+// AddTranslation 为文档 doc 添加或替换 lang 的译文 text, doc 成为多语言文档.
+// doc 为经典的双语文档时, 原有译文成为不带 lang 的译文.
+func AddTranslation(doc *ast.CommentGroup, lang, text string) {
+	var (
+		origin string
+		trans  []Translation
+	)
+	if s := doc.Text(); HasLangs(s) {
+		origin, trans = SplitTranslations(s)
+	} else if origin, s = SplitComments(s); origin == "" {
+		origin = s
+	} else if s = trimText(s); s != "" {
+		trans = []Translation{{"", s}}
+	}
+	found := false
+	for i := range trans {
+		if trans[i].Lang == lang {
+			trans[i].Text, found = text, true
+		}
+	}
+	if !found {
+		trans = append(trans, Translation{lang, text})
+	}
+	ReplaceDoc(doc, commentGroup(JoinTranslations(origin, trans)))
+}
+
+// MergeLangs 以 lang 的翻译文档 source 为 target 中同名声明的文档添加译文.
+// source 为多语言文档时 lang 被忽略, 添加其中全部带 lang 的译文.
+// 返回添加的译文数量. Godocu 风格的 source 会调用 ClearComments.
+func MergeLangs(target, source *ast.File, lang string) (n int) {
+	docs := docsOf(target)
+	seen := make(map[string]int)
+	eachTrans(source, func(ident, _, t string, doc *ast.CommentGroup) {
+		i := seen[ident]
+		seen[ident]++
+		if i >= len(docs[ident]) {
+			return
+		}
+		var trans []Translation
+		if text := doc.Text(); HasLangs(text) {
+			_, trans = SplitTranslations(text)
+		} else if t != "" {
+			trans = []Translation{{lang, t}}
+		}
+		for _, t := range trans {
+			if t.Lang != "" {
+				AddTranslation(docs[ident][i], t.Lang, t.Text)
+				n++
+			}
+		}
+	})
+	return
+}
@@ -0,0 +1,119 @@
+package docu
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestTranslations(t *testing.T) {
+	const text = `A is a.
+
+___GoDocu_Dividing_line___ zh_CN
+
+A 是 a.
+
+___GoDocu_Dividing_line___ zh_TW
+
+A 是 a (TW).
+`
+	if !HasLangs(text) || HasLangs("A is a.\n"+GoDocu_Dividing_line+"\nA 是 a.\n") {
+		t.Fatal("HasLangs")
+	}
+	origin, trans := SplitTranslations(text)
+	if origin != "A is a.\n" || len(trans) != 2 ||
+		trans[0] != (Translation{"zh_CN", "A 是 a.\n"}) ||
+		trans[1] != (Translation{"zh_TW", "A 是 a (TW).\n"}) {
+		t.Fatalf("SplitTranslations = %q, %q", origin, trans)
+	}
+	if s := JoinTranslations(origin, trans); s != text {
+		t.Errorf("JoinTranslations = %q", s)
+	}
+
+	sel := SelectTranslations(trans, []string{"zh_TW", "ja"})
+	if len(sel) != 1 || sel[0].Lang != "zh_TW" {
+		t.Errorf("SelectTranslations = %q", sel)
+	}
+	if s := SelectLangs(text, []string{"zh_TW"}); s !=
+		"A is a.\n\n"+GoDocu_Dividing_line+"\n\nA 是 a (TW).\n" {
+		t.Errorf("SelectLangs = %q", s)
+	}
+	if s := SelectLangs(text, []string{"ja"}); s != "A is a.\n" {
+		t.Errorf("SelectLangs = %q", s)
+	}
+
+	Langs = []string{"zh_TW"}
+	defer func() { Langs = nil }()
+	if o, s := SplitComments(text); o != origin || s != "A 是 a (TW).\n" {
+		t.Errorf("SplitComments = %q, %q", o, s)
+	}
+}
+
+func TestMergeLangs(t *testing.T) {
+	const src = `package p
+
+// A is a.
+func A()
+
+// B is b.
+func B()
+`
+	target, err := parser.ParseFile(token.NewFileSet(), "src.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zh := parseGodocu(t, `package p
+
+// A is a.
+
+// A 是 a.
+func A()
+
+// B is b.
+func B()
+`)
+	tw := parseGodocu(t, `package p
+
+// A is a.
+
+// A 是 a (TW).
+func A()
+
+// B is b.
+
+// B 是 b (TW).
+func B()
+`)
+	if n := MergeLangs(target, zh, "zh_CN"); n != 1 {
+		t.Errorf("MergeLangs zh_CN = %d", n)
+	}
+	if n := MergeLangs(target, tw, "zh_TW"); n != 2 {
+		t.Errorf("MergeLangs zh_TW = %d", n)
+	}
+	_, trans := SplitTranslations(target.Decls[0].(*ast.FuncDecl).Doc.Text())
+	if len(trans) != 2 || trans[0].Lang != "zh_CN" || trans[1].Lang != "zh_TW" {
+		t.Fatalf("MergeLangs A = %q", trans)
+	}
+
+	var buf bytes.Buffer
+	if err = Fprint(&buf, target); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "// "+GoDocu_Dividing_line+" zh_TW\n//\n// B 是 b (TW).\nfunc B()") {
+		t.Errorf("Fprint =\n%s", out)
+	}
+
+	// 输出的多语言文档可以再次合并
+	mul := parseGodocu(t, out)
+	if p := TranslationProgress(mul); p != 100 {
+		t.Errorf("TranslationProgress = %d", p)
+	}
+	target, _ = parser.ParseFile(token.NewFileSet(), "src.go", src, parser.ParseComments)
+	if n := MergeLangs(target, mul, ""); n != 3 {
+		t.Errorf("MergeLangs mul = %d", n)
+	}
+}
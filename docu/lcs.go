@@ -0,0 +1,330 @@
+package docu
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// edit 为编辑脚本中的一项, op 为 ' ' 相同, '-' 仅 a 具有, '+' 仅 b 具有.
+// i, j 分别为该项在 a, b 中的下标, 不具有时为 -1.
+type edit struct {
+	op   byte
+	i, j int
+}
+
+// maxLCS 限制 LCS 矩阵的大小, 超出时剩余部分整体视作删除和添加.
+const maxLCS = 1 << 22
+
+// editScript 基于最长公共子序列计算 a 到 b 的编辑脚本.
+// 同一位置的删除总在添加之前.
+func editScript(a, b []string) []edit {
+	var es []edit
+	// 剔除相同的前后缀, 减小矩阵
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		es = append(es, edit{' ', pre, pre})
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre &&
+		a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	ma, mb := a[pre:len(a)-suf], b[pre:len(b)-suf]
+	n, m := len(ma), len(mb)
+
+	if n*m > maxLCS {
+		for i := range ma {
+			es = append(es, edit{'-', pre + i, -1})
+		}
+		for j := range mb {
+			es = append(es, edit{'+', -1, pre + j})
+		}
+	} else {
+		// l[i][j] 为 ma[i:], mb[j:] 的 LCS 长度
+		l := make([][]int32, n+1)
+		for i := range l {
+			l[i] = make([]int32, m+1)
+		}
+		for i := n - 1; i >= 0; i-- {
+			for j := m - 1; j >= 0; j-- {
+				if ma[i] == mb[j] {
+					l[i][j] = l[i+1][j+1] + 1
+				} else if l[i+1][j] >= l[i][j+1] {
+					l[i][j] = l[i+1][j]
+				} else {
+					l[i][j] = l[i][j+1]
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < n || j < m {
+			switch {
+			case i < n && j < m && ma[i] == mb[j]:
+				es = append(es, edit{' ', pre + i, pre + j})
+				i++
+				j++
+			case j == m || i < n && l[i+1][j] >= l[i][j+1]:
+				es = append(es, edit{'-', pre + i, -1})
+				i++
+			default:
+				es = append(es, edit{'+', -1, pre + j})
+				j++
+			}
+		}
+	}
+
+	for k := suf; k > 0; k-- {
+		es = append(es, edit{' ', len(a) - k, len(b) - k})
+	}
+	return es
+}
+
+// docLines 把文档 text 拆分为行, 忽略结尾的换行.
+func docLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// hunks 返回 changed 中的变化项及其前后 context 项所组成的区间.
+func hunks(changed []bool, context int) (ranges [][2]int) {
+	for k := 0; k < len(changed); k++ {
+		if !changed[k] {
+			continue
+		}
+		start, end := k-context, k+context+1
+		if start < 0 {
+			start = 0
+		}
+		if n := len(ranges); n != 0 && start <= ranges[n-1][1] {
+			start = ranges[n-1][0]
+			ranges = ranges[:n-1]
+		}
+		if end > len(changed) {
+			end = len(changed)
+		}
+		ranges = append(ranges, [2]int{start, end})
+	}
+	return
+}
+
+// hunkRange 返回 unified diff 头部中的行号范围.
+func hunkRange(first, count int) string {
+	if count == 0 {
+		first--
+	}
+	if count == 1 {
+		return strconv.Itoa(first + 1)
+	}
+	return strconv.Itoa(first+1) + "," + strconv.Itoa(count)
+}
+
+// LineDiff 以 unified diff 格式输出文本 source, target 的行差异,
+// context 为变化行前后保留的行数, 每行前缀 prefix.
+func LineDiff(w io.Writer, prefix, source, target string, context int) error {
+	a, b := docLines(source), docLines(target)
+	es := editScript(a, b)
+	changed := make([]bool, len(es))
+	for k, e := range es {
+		changed[k] = e.op != ' '
+	}
+
+	for _, r := range hunks(changed, context) {
+		// 计算区间起始行号和行数
+		ai, bj, an, bn := -1, -1, 0, 0
+		for _, e := range es[r[0]:r[1]] {
+			if e.op != '+' {
+				if ai == -1 {
+					ai = e.i
+				}
+				an++
+			}
+			if e.op != '-' {
+				if bj == -1 {
+					bj = e.j
+				}
+				bn++
+			}
+		}
+		if ai == -1 {
+			ai = lineAt(es, r[0], true)
+		}
+		if bj == -1 {
+			bj = lineAt(es, r[0], false)
+		}
+		err := fprint(w, prefix, "@@ -", hunkRange(ai, an), " +", hunkRange(bj, bn), " @@\n")
+		for _, e := range es[r[0]:r[1]] {
+			if err != nil {
+				return err
+			}
+			if e.op == '+' {
+				err = fprint(w, prefix, "+", b[e.j], "\n")
+			} else {
+				err = fprint(w, prefix, string(e.op), a[e.i], "\n")
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lineAt 返回 es[k] 之前 a 或 b 中已经过的行数.
+func lineAt(es []edit, k int, inA bool) int {
+	for k--; k >= 0; k-- {
+		if inA && es[k].i != -1 {
+			return es[k].i + 1
+		}
+		if !inA && es[k].j != -1 {
+			return es[k].j + 1
+		}
+	}
+	return 0
+}
+
+// isCJK 返回 r 是否为不以空白分词的 CJK 字符.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// splitWords 拆分 s 为单词, 返回各单词在 s 中的起止位置.
+// 连续的字母, 数字构成一个单词, CJK 字符和标点各自成为一个单词, 空白为分隔.
+func splitWords(s string) (spans [][2]int) {
+	start := -1
+	for pos, r := range s {
+		word := !isCJK(r) && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+		if start != -1 && !word {
+			spans = append(spans, [2]int{start, pos})
+			start = -1
+		}
+		if word {
+			if start == -1 {
+				start = pos
+			}
+		} else if !unicode.IsSpace(r) {
+			spans = append(spans, [2]int{pos, pos + utf8.RuneLen(r)})
+		}
+	}
+	if start != -1 {
+		spans = append(spans, [2]int{start, len(s)})
+	}
+	return
+}
+
+func isSpaceAt(s string, i int) bool {
+	return i >= 0 && i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n')
+}
+
+func wordsOf(s string, spans [][2]int) []string {
+	words := make([]string, len(spans))
+	for k, sp := range spans {
+		words[k] = s[sp[0]:sp[1]]
+	}
+	return words
+}
+
+// WordDiff 以单词差异形式输出文本 source, target 中发生变化的行.
+// 按 target 的排版输出, 删除的单词标记为 "[-...-]", 添加的标记为 "{+...+}".
+// 空白和换行不参与对比, 因此只是排版不同时没有输出.
+// context 为变化行前后保留的行数, 每行前缀 prefix.
+func WordDiff(w io.Writer, prefix, source, target string, context int) error {
+	sspans, tspans := splitWords(source), splitWords(target)
+	a, b := wordsOf(source, sspans), wordsOf(target, tspans)
+	es := editScript(a, b)
+
+	var buf strings.Builder
+	var changed []bool
+	// deleted 为待输出删除单词在 source 中的起止位置
+	deleted := [2]int{-1, -1}
+	pos, line, inserting := 0, 0, false
+
+	mark := func() {
+		for len(changed) <= line {
+			changed = append(changed, false)
+		}
+		changed[line] = true
+	}
+	// gap 输出单词之间的间隔 s 和待输出的删除单词, next 为下一个单词的 op.
+	// 添加标记不跨行, 不包含删除单词. 删除单词依照 source 中前后的空白放置.
+	gap := func(s string, next byte) {
+		if inserting && (next != '+' || deleted[0] != -1 || strings.Contains(s, "\n")) {
+			buf.WriteString("+}")
+			inserting = false
+		}
+		if deleted[0] == -1 {
+			buf.WriteString(s)
+			line += strings.Count(s, "\n")
+			return
+		}
+
+		text := "[-" + strings.Join(strings.Fields(source[deleted[0]:deleted[1]]), " ") + "-]"
+		if space := isSpaceAt(source, deleted[0]-1); !space || strings.Contains(s, "\n") {
+			// 紧随前一个单词或位于行尾
+			mark()
+			if space && buf.Len() != 0 {
+				buf.WriteByte(' ')
+			}
+			buf.WriteString(text)
+			buf.WriteString(s)
+			line += strings.Count(s, "\n")
+		} else {
+			buf.WriteString(s)
+			if s == "" && buf.Len() != 0 {
+				buf.WriteByte(' ')
+			}
+			line += strings.Count(s, "\n")
+			mark()
+			buf.WriteString(text)
+			if next == ' ' && isSpaceAt(source, deleted[1]) {
+				buf.WriteByte(' ')
+			}
+		}
+		deleted[0] = -1
+	}
+
+	for _, e := range es {
+		if e.op == '-' {
+			if deleted[0] == -1 {
+				deleted[0] = sspans[e.i][0]
+			}
+			deleted[1] = sspans[e.i][1]
+			continue
+		}
+		sp := tspans[e.j]
+		gap(target[pos:sp[0]], e.op)
+		if e.op == '+' {
+			mark()
+			if !inserting {
+				buf.WriteString("{+")
+				inserting = true
+			}
+		}
+		buf.WriteString(b[e.j])
+		pos = sp[1]
+	}
+	gap(strings.TrimSuffix(target[pos:], "\n"), 0)
+
+	lines := strings.Split(buf.String(), "\n")
+	for len(changed) < len(lines) {
+		changed = append(changed, false)
+	}
+	for _, r := range hunks(changed, context) {
+		err := fprint(w, prefix, "@@ +", hunkRange(r[0], r[1]-r[0]), " @@\n")
+		for _, s := range lines[r[0]:r[1]] {
+			if err != nil {
+				return err
+			}
+			err = fprint(w, prefix, s, "\n")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package docu
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLineDiff(t *testing.T) {
+	source := "a\nb\nc\nd\ne\nf\ng\nh\n"
+	target := "a\nb\nc\nD\ne\nf\ng\nh\ni\n"
+	want := `@@ -3,3 +3,3 @@
+ c
+-d
++D
+ e
+@@ -8 +8,2 @@
+ h
++i
+`
+	var buf bytes.Buffer
+	if err := LineDiff(&buf, "", source, target, 1); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want {
+		t.Errorf("LineDiff got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestWordDiff(t *testing.T) {
+	tests := []struct {
+		source, target, want string
+	}{
+		{"same words\nwrapped", "same\nwords wrapped", ""},
+		{"Rename renames oldpath\nto newpath.", "Rename renames a file.",
+			"@@ +1 @@\nRename renames [-oldpath to newpath-]{+a file+}.\n"},
+		{"one two\nthree", "one three\nfour",
+			"@@ +1,2 @@\none [-two-] three\n{+four+}\n"},
+		{"这是中文文档.", "这是翻译文档.",
+			"@@ +1 @@\n这是[-中文-]{+翻译+}文档.\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := WordDiff(&buf, "", tt.source, tt.target, 1); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("WordDiff(%q, %q) got:\n%q\nwant:\n%q", tt.source, tt.target, buf.String(), tt.want)
+		}
+	}
+}
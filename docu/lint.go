@@ -0,0 +1,223 @@
+package docu
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LintWidth 为 Godocu 风格文档注释行的最大宽度, 同 Fprint 换行的宽度.
+// tab 按四个长度计算, 多字节按两个长度计算.
+const LintWidth = 80
+
+// LintRecord 表示 Lint 发现的一个问题.
+type LintRecord struct {
+	Filename string
+	Line     int `json:",omitempty"` // 0 表示整个文件
+	Message  string
+}
+
+func (r LintRecord) String() string {
+	if r.Line == 0 {
+		return r.Filename + ": " + r.Message
+	}
+	return fmt.Sprintf("%s:%d: %s", r.Filename, r.Line, r.Message)
+}
+
+// LintDir 检查目录 dir 中的 Go 文件命名, 要求都符合 Godocu 命名风格,
+// 每个包只有一个文档, 且 lang 相同. dir 不存在时返回 nil.
+func LintDir(dir string) (records []LintRecord) {
+	fis, _ := ioutil.ReadDir(dir)
+	var lang string
+	kinds := make(map[string]string)
+	for _, fi := range fis {
+		name := fi.Name()
+		if fi.IsDir() || !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		filename := filepath.Join(dir, name)
+		if !IsNormalName(name) {
+			records = append(records,
+				LintRecord{filename, 0, "file name is not Godocu style"})
+			continue
+		}
+		l, kind := LangOf(name), name[:strings.IndexByte(name, '_')]
+		if lang == "" {
+			lang = l
+		} else if l != lang {
+			records = append(records,
+				LintRecord{filename, 0, "lang " + l + " differs from " + lang + " in the same directory"})
+		}
+		if kinds[kind] != "" {
+			records = append(records,
+				LintRecord{filename, 0, "multiple documents for package, also " + kinds[kind]})
+		} else {
+			kinds[kind] = name
+		}
+	}
+	return
+}
+
+// isIgnoreHeader 返回 line 是否为排除构建的约束, Fprint 输出的是 "// +build ingore".
+func isIgnoreHeader(line string) bool {
+	switch strings.Join(strings.Fields(line), " ") {
+	case "// +build ignore", "// +build ingore", "//go:build ignore", "//go:build ingore":
+		return true
+	}
+	return false
+}
+
+// LintFile 检查 Godocu 风格文档 filename, 返回按行排序的问题.
+// src 同 parser.ParseFile. source 非 nil 时检查 filename 中的声明是否都存在于 source.
+//
+// 检查内容:
+//
+//	排除构建的 "// +build ignore" 头
+//	原文注释能否被 OriginDoc 对应到译文
+//	多余或不完整的 GoDocu_Dividing_line
+//	注释行宽度超过 LintWidth, 含网址的行和代码行除外
+//	source 中不存在的声明
+func LintFile(filename string, src interface{}, source *ast.File) ([]LintRecord, error) {
+	code, err := readSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, code, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []LintRecord
+	report := func(pos token.Pos, msg string) {
+		records = append(records, LintRecord{filename, fset.Position(pos).Line, msg})
+	}
+
+	header := code[:fset.Position(file.Package).Offset]
+	ignored := false
+	for _, line := range strings.Split(string(header), "\n") {
+		ignored = ignored || isIgnoreHeader(line)
+	}
+	if !ignored {
+		records = append(records, LintRecord{filename, 1, `missing "// +build ignore" header`})
+	}
+
+	lines := bytes.Split(code, []byte(nl))
+	for _, cg := range file.Comments {
+		for _, c := range cg.List {
+			line := lines[fset.Position(c.Slash).Line-1]
+			s := strings.TrimSpace(string(line))
+			// 只检查独占一行的注释, 忽略代码行
+			if !strings.HasPrefix(s, c.Text) || strings.HasPrefix(c.Text, "//\t") ||
+				strings.HasPrefix(c.Text, "// \t") || UrlPos(s) != -1 {
+				continue
+			}
+			if wrappedBefor(string(line), LintWidth) != len(line) {
+				report(c.Slash, fmt.Sprintf("line exceeds %d columns", LintWidth))
+			}
+		}
+	}
+
+	// 文档, 尾注释
+	var docs []*ast.CommentGroup
+	attached := make(map[*ast.CommentGroup]bool)
+	eachDoc(file, func(_ string, doc *ast.CommentGroup) {
+		docs = append(docs, doc)
+	})
+	ast.Inspect(file, func(node ast.Node) bool {
+		if cg, ok := node.(*ast.CommentGroup); ok {
+			attached[cg] = true
+		}
+		return true
+	})
+	sort.Sort(sortComments(docs))
+
+	pkgLine := fset.Position(file.Name.Pos()).Line
+	for _, cg := range file.Comments {
+		if cg.End() < file.Package || fset.Position(cg.Pos()).Line == pkgLine {
+			continue
+		}
+		// 多语言文档中每个 lang 的分割线只能出现一次
+		langs := make(map[string]bool)
+		dividers := 0
+		for _, c := range cg.List {
+			if lang, ok := dividingLang(strings.TrimPrefix(c.Text, "//")); ok {
+				dividers++
+				if !attached[cg] || langs[lang] {
+					report(c.Slash, "dangling "+GoDocu_Dividing_line)
+				}
+				langs[lang] = true
+			}
+		}
+		if attached[cg] {
+			if dividers != 0 && dividers == len(langs) {
+				origin, trans := SplitTranslations(cg.Text())
+				if origin == "" || len(trans) != dividers {
+					report(cg.Pos(), "empty origin or translation around "+GoDocu_Dividing_line)
+				}
+			}
+			continue
+		}
+		// 原文应紧邻其后的文档
+		i := sort.Search(len(docs), func(i int) bool { return docs[i].Pos() > cg.End() })
+		if i == len(docs) || !isOrigin(cg, docs[i].Pos()) {
+			report(cg.Pos(), "comment is not paired with a translation")
+		} else if strings.Contains(docs[i].Text(), GoDocu_Dividing_line) {
+			report(cg.Pos(), "comment precedes a merged document")
+		}
+	}
+
+	if source != nil {
+		declared := make(map[string]bool)
+		eachDeclIdent(source, func(ident string, _ token.Pos) {
+			declared[ident] = true
+		})
+		eachDeclIdent(file, func(ident string, pos token.Pos) {
+			if !declared[ident] {
+				report(pos, ident+" is not declared in source")
+			}
+		})
+	}
+
+	sort.Stable(sortLintRecords(records))
+	return records, nil
+}
+
+// eachDeclIdent 以 file 中顶级声明的标识符和位置调用 fn, 方法形如 "*Type.Method".
+func eachDeclIdent(file *ast.File, fn func(ident string, pos token.Pos)) {
+	for _, node := range file.Decls {
+		switch n := node.(type) {
+		case *ast.GenDecl:
+			for _, spec := range n.Specs {
+				switch s := spec.(type) {
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						fn(name.Name, name.Pos())
+					}
+				case *ast.TypeSpec:
+					fn(s.Name.Name, s.Pos())
+				}
+			}
+		case *ast.FuncDecl:
+			fn(FuncIdentLit(n), n.Pos())
+		}
+	}
+}
+
+type sortComments []*ast.CommentGroup
+
+func (s sortComments) Len() int           { return len(s) }
+func (s sortComments) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s sortComments) Less(i, j int) bool { return s[i].Pos() < s[j].Pos() }
+
+type sortLintRecords []LintRecord
+
+func (s sortLintRecords) Len() int           { return len(s) }
+func (s sortLintRecords) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s sortLintRecords) Less(i, j int) bool { return s[i].Line < s[j].Line }
@@ -0,0 +1,87 @@
+package docu
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestLintFile(t *testing.T) {
+	const src = `package p
+
+// A is a.
+func A()
+
+// B is b.
+func B()
+`
+	const dst = `// +build ingore
+
+package p
+
+// A is a.
+
+// A 是 a.
+func A()
+
+// B is b.
+//
+// ___GoDocu_Dividing_line___
+func B()
+
+// C 是 c, 这一行非常非常非常非常非常非常非常非常非常非常非常非常非常非常非常非常长.
+func C()
+
+// Orphan.
+`
+	source, err := parser.ParseFile(token.NewFileSet(), "src.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := LintFile("doc_zh_CN.go", dst, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []LintRecord{
+		{"doc_zh_CN.go", 10, "empty origin or translation around " + GoDocu_Dividing_line},
+		{"doc_zh_CN.go", 15, "line exceeds 80 columns"},
+		{"doc_zh_CN.go", 16, "C is not declared in source"},
+		{"doc_zh_CN.go", 18, "comment is not paired with a translation"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("LintFile = %v", records)
+	}
+	for i := range want {
+		if records[i] != want[i] {
+			t.Errorf("LintFile[%d] = %v, want %v", i, records[i], want[i])
+		}
+	}
+
+	// 多语言文档中每个 lang 的分割线各出现一次
+	const mul = `// +build ingore
+
+package p
+
+// A is a.
+//
+// ___GoDocu_Dividing_line___ zh_CN
+//
+// A 是 a.
+//
+// ___GoDocu_Dividing_line___ zh_TW
+//
+// A 是 a.
+//
+// ___GoDocu_Dividing_line___ zh_TW
+func A()
+`
+	records, err = LintFile("doc_mul.go", mul, nil)
+	if err != nil || len(records) != 1 || records[0].Line != 15 {
+		t.Errorf("LintFile multilingual = %v, %v", records, err)
+	}
+
+	records, err = LintFile("doc_zh_CN.go", "package p\n", nil)
+	if err != nil || len(records) != 1 || records[0].Line != 1 {
+		t.Errorf("LintFile without header = %v, %v", records, err)
+	}
+}
@@ -37,5 +37,11 @@ type Info struct {
 	Synopsis string // 自动提取的一句话包摘要
 	// Readme 该包下 readme 文件名, 自动提取.
 	Readme   string `json:",omitempty"`
-	Progress int    // 翻译完成度
+	Progress int    // 按段落长度加权的翻译完成度
+	// GroupProgress 按注释分组计算的翻译完成度, 即早期的 Progress.
+	GroupProgress int
+	// Stale 具有过期标记的译文个数, 参见 GoDocu_Stale_line.
+	Stale int `json:",omitempty"`
+	// Deprecated 已废弃的声明个数, 参见 IsDeprecated.
+	Deprecated int `json:",omitempty"`
 }
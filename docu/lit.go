@@ -1,9 +1,12 @@
 package docu
 
 import (
-	"fmt"
+	"bytes"
 	"go/ast"
+	"go/scanner"
+	"go/token"
 	"go/types"
+	"strconv"
 )
 
 // DeclIdentLit 返回返 decl 第一个 ast.Spec 的 Ident 字面描述.
@@ -34,17 +37,31 @@ func SpecIdentLit(spec ast.Spec) (lit string) {
 	return
 }
 
-// SpecTypeIdentLit 返回 spec 类型 Ident 字面描述.
+// SpecTypeLit 返回 spec 类型 Ident 字面描述. TypeSpec 含类型参数.
 func SpecTypeLit(spec ast.Spec) (lit string) {
 	switch n := spec.(type) {
 	case *ast.ValueSpec:
-		lit = types.ExprString(n.Type)
+		if n.Type != nil {
+			lit = types.ExprString(n.Type)
+		}
 	case *ast.TypeSpec:
 		lit = types.ExprString(n.Type)
+		if tparams := TypeParamsLit(n.TypeParams); tparams != "" {
+			lit = tparams + " " + lit
+		}
 	}
 	return
 }
 
+// TypeParamsLit 返回类型参数列表 list 的字面值, 形如 "[K comparable, V any]".
+// 如果 list 为空返回 "".
+func TypeParamsLit(list *ast.FieldList) string {
+	if list == nil || len(list.List) == 0 {
+		return ""
+	}
+	return "[" + FieldListLit(list) + "]"
+}
+
 // SpecDoc 返回 spec 的 Doc,Comment 字段
 func SpecDoc(spec ast.Spec) *ast.CommentGroup {
 	if spec == nil {
@@ -74,19 +91,109 @@ func SpecComment(spec ast.Spec) (*ast.CommentGroup, *ast.CommentGroup) {
 }
 
 // RecvIdentLit 返回 decl.Recv Ident 字面描述. 不含 decl.Name.
+// 泛型接收者不含类型参数, 比如 "*List[T]" 的返回值为 "*List".
 func RecvIdentLit(decl *ast.FuncDecl) (lit string) {
 	if decl.Recv == nil || len(decl.Recv.List) == 0 {
 		return
 	}
-	switch expr := decl.Recv.List[0].Type.(type) {
+	expr := decl.Recv.List[0].Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		lit, expr = "*", star.X
+	}
+	if ident := recvTypeIdent(expr); ident != nil {
+		return lit + ident.String()
+	}
+	return ""
+}
+
+// recvTypeIdent 返回接收者类型 expr 的类型名, 剔除泛型类型参数.
+func recvTypeIdent(expr ast.Expr) *ast.Ident {
+	switch n := expr.(type) {
 	case *ast.StarExpr:
-		if x, ok := expr.X.(fmt.Stringer); ok {
-			lit = "*" + x.String()
-		}
+		return recvTypeIdent(n.X)
+	case *ast.ParenExpr:
+		return recvTypeIdent(n.X)
+	case *ast.IndexExpr:
+		return recvTypeIdent(n.X)
+	case *ast.IndexListExpr:
+		return recvTypeIdent(n.X)
 	case *ast.Ident:
-		lit = expr.String()
+		return n
+	}
+	return nil
+}
+
+// recvTypeParams 返回方法 decl 接收者的类型参数名称, 比如 "*List[T]" 的 T.
+func recvTypeParams(decl *ast.FuncDecl) (names []string) {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return
+	}
+	expr := decl.Recv.List[0].Type
+	for {
+		switch n := expr.(type) {
+		case *ast.StarExpr:
+			expr = n.X
+			continue
+		case *ast.ParenExpr:
+			expr = n.X
+			continue
+		case *ast.IndexExpr:
+			names = append(names, types.ExprString(n.Index))
+		case *ast.IndexListExpr:
+			for _, index := range n.Indices {
+				names = append(names, types.ExprString(index))
+			}
+		}
+		return
 	}
-	return
+}
+
+// recvParamsKey 以位置替换字面值 lit 中方法 decl 接收者的类型参数名称,
+// 用于比较只是接收者类型参数改名的方法, 比如 "*List[T]" 和 "*List[E]".
+func recvParamsKey(decl *ast.FuncDecl, lit string) string {
+	names := recvTypeParams(decl)
+	if len(names) == 0 {
+		return lit
+	}
+	pos := make(map[string]int, len(names))
+	for i, name := range names {
+		if name != "_" {
+			pos[name] = i
+		}
+	}
+
+	var (
+		s    scanner.Scanner
+		buf  bytes.Buffer
+		last int
+		prev token.Token
+	)
+	file := token.NewFileSet().AddFile("", -1, len(lit))
+	s.Init(file, []byte(lit), nil, 0)
+	for {
+		p, tok, text := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		// 跳过限定标识符 pkg.T 中的 T
+		if i, ok := pos[text]; ok && tok == token.IDENT && prev != token.PERIOD {
+			offset := file.Offset(p)
+			buf.WriteString(lit[last:offset])
+			buf.WriteString("$" + strconv.Itoa(i))
+			last = offset + len(text)
+		}
+		prev = tok
+	}
+	buf.WriteString(lit[last:])
+	return buf.String()
+}
+
+// RecvLit 返回 decl.Recv 类型的字面描述, 含泛型类型参数. 比如 "*List[T]".
+func RecvLit(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return ""
+	}
+	return types.ExprString(decl.Recv.List[0].Type)
 }
 
 // FuncIdentLit 返回 FuncDecl 的 Ident 字面描述.
@@ -112,7 +219,12 @@ func FuncResultsLit(decl *ast.FuncDecl) (lit string) {
 	return
 }
 
-// FuncLit 返回 FuncDecl 的字面描述. 不含 decl.Name.
+// FuncTypeParamsLit 返回泛型函数 decl 的类型参数字面描述, 比如 "[T any]".
+func FuncTypeParamsLit(decl *ast.FuncDecl) string {
+	return TypeParamsLit(decl.Type.TypeParams)
+}
+
+// FuncLit 返回 FuncDecl 的字面描述. 不含接收者名称.
 func FuncLit(decl *ast.FuncDecl) (lit string) {
 	lit = FuncResultsLit(decl)
 	if lit == "" {
@@ -120,10 +232,11 @@ func FuncLit(decl *ast.FuncDecl) (lit string) {
 	} else {
 		lit = FuncParamsLit(decl) + " " + lit
 	}
+	lit = FuncTypeParamsLit(decl) + lit
 	if decl.Name != nil {
 		lit = decl.Name.String() + lit
 	}
-	recv := RecvIdentLit(decl)
+	recv := RecvLit(decl)
 	if recv == "" {
 		lit = "func " + lit
 	} else {
@@ -140,6 +253,7 @@ func MethodLit(decl *ast.FuncDecl) (lit string) {
 	} else {
 		lit = FuncParamsLit(decl) + " " + lit
 	}
+	lit = FuncTypeParamsLit(decl) + lit
 	if decl.Name != nil {
 		lit = decl.Name.String() + lit
 	}
@@ -157,6 +271,8 @@ func MethodLit(decl *ast.FuncDecl) (lit string) {
 //  ast.FuncDecl.Recv.List
 //	ast.FuncDecl.Type.Params
 //	ast.FuncDecl.Type.Results
+//	ast.FuncType.TypeParams
+//	ast.TypeSpec.TypeParams
 //
 func FieldListLit(list *ast.FieldList) (lit string) {
 	if list == nil || len(list.List) == 0 {
@@ -186,9 +302,9 @@ func FieldLit(field *ast.Field) (lit string) {
 	}
 	if field.Type != nil {
 		if lit == "" {
-			lit = types.ExprString(field.Type)
+			lit = exprLit(field.Type)
 		} else {
-			lit += " " + types.ExprString(field.Type)
+			lit += " " + exprLit(field.Type)
 		}
 	}
 	return
@@ -217,7 +333,8 @@ func ImportsString(is []*ast.ImportSpec) (s string) {
 	for i, im := range is {
 		if i == 0 {
 			s += "import (\n\t" + im.Path.Value + nl
-		} else {
+		} else if im.Path.Value != is[i-1].Path.Value {
+			// 合并多个文件时会有重复的 import
 			s += "\t" + im.Path.Value + nl
 		}
 	}
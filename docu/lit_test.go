@@ -0,0 +1,69 @@
+package docu
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+const testGenericSource = `package generic
+
+type List[T any] struct{ Head *T }
+
+type Pair[K comparable, V any] struct{}
+
+func New[T any]() *List[T]
+
+func Map[T, U any](s []T, f func(T) U) []U
+
+func (l *List[T]) Push(v T)
+
+func (l *List[E]) Len() int
+
+func (p Pair[K, V]) Get(k K) V
+`
+
+func TestGenericLit(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "generic.go", testGenericSource, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Index(file)
+
+	tests := []struct {
+		ident, lit, method string
+	}{
+		{"Map", "func Map[T, U any](s []T, f func(T) U) []U", "func Map[T, U any](s []T, f func(T) U) []U"},
+		{"New", "func New[T any]() *List[T]", "func New[T any]() *List[T]"},
+		{"*List.Len", "func (*List[E]) Len() int", "func (l *List[E]) Len() int"},
+		{"*List.Push", "func (*List[T]) Push(v T)", "func (l *List[T]) Push(v T)"},
+		{"Pair.Get", "func (Pair[K, V]) Get(k K) V", "func (p Pair[K, V]) Get(k K) V"},
+	}
+	decls := SortDecl(file.Decls)
+	for _, tt := range tests {
+		decl := decls.SearchFunc(tt.ident)
+		if decl == nil {
+			t.Errorf("SearchFunc(%q) = nil", tt.ident)
+			continue
+		}
+		if lit := FuncLit(decl); lit != tt.lit {
+			t.Errorf("FuncLit(%q) = %q want %q", tt.ident, lit, tt.lit)
+		}
+		if lit := MethodLit(decl); lit != tt.method {
+			t.Errorf("MethodLit(%q) = %q want %q", tt.ident, lit, tt.method)
+		}
+	}
+
+	if fn := decls.SearchConstructor("List"); fn == nil || fn.Name.String() != "New" {
+		t.Errorf("SearchConstructor(%q) = %v", "List", fn)
+	}
+
+	spec, _, _ := decls.SearchSpec("Pair")
+	if lit := SpecTypeLit(spec); lit != "[K comparable, V any] struct{}" {
+		t.Errorf("SpecTypeLit(%q) = %q", "Pair", lit)
+	}
+	if ts, _ := spec.(*ast.TypeSpec); !exportedTypeSpecFilter(ts, nil) {
+		t.Errorf("exportedTypeSpecFilter(%q) = false", "Pair")
+	}
+}
@@ -4,13 +4,19 @@ const MarkdownTemplate = `{{define "echo"}}
 ` + "```go" + `
 {{.}}
 ` + "```" + `
-{{end}}{{/*
+{{end}}{{define "example"}}
+#### Example{{if $s := exampleSuffix .}} ({{$s}}){{end}}
+
+{{selectLangs .Doc.Text}}{{template "echo" exampleCode .}}{{end}}{{/*
 此模板输出 Markdown 格式.
 模板传入 Data 实例作为模板执行数据. 并映射了 docu.FuncsMap.
+各部分以 block 定义, 执行数据都是 Data, 可用 define 单独覆盖:
+header, consts, vars, funcs, types, notes, footer.
+示例函数以 example 输出, 方法 ExamplesOf 返回属于某个声明的示例.
 */}}{{if eq .Key .ImportPath}}{{/*
 模板必须通过 Type 方法(任意位置)设定输出文件扩展名, 否则会抛弃输出.
 在这个例子中只输出标准的 doc 文档, 忽略 main, test 文档.
-*/}}{{$.Type "md"}}{{$this := .File}}# {{base .ImportPath}}
+*/}}{{$.Type "md"}}{{block "header" .}}{{$this := .File}}# {{base .ImportPath}}
 
 {{/*
 函数 progress 返回文档翻译完成度, 值为 0-100. 该值有多种用途.
@@ -21,46 +27,57 @@ const MarkdownTemplate = `{{define "echo"}}
 函数 canonicalImportPaths 返回文档权威导入路径.
 */}}{{if $x := canonicalImportPaths $this}}{{template "echo" $x}}{{end}}{{/*
 主文档以及各种声明
-*/}}{{if $this.Doc}}{{wrap $this.Doc.Text}}{{end}}{{/*
+*/}}{{if $this.Doc}}{{wrap (selectLangs $this.Doc.Text)}}{{end}}{{/*
+*/}}{{range .Normal.ExamplesOf ""}}{{template "example" .}}{{end}}{{end}}{{/*
+
+方法 Normal 返回按 godoc 习惯分组的声明, 类型声明包含相关的常量, 变量, 构造函数和方法
 
 常量
-*/}}{{range $i, $x := decls $this.Decls .CONST}}{{if eq $i 0}}
+*/}}{{block "consts" .}}{{range $i, $x := .Normal.Consts}}{{if eq $i 0}}
 ## const
 
-{{end}}{{$.Text $x}}{{template "echo" $.Code $x}}{{end}}{{/*
-*/}}{{range $i, $x := decls $this.Decls .VAR}}{{if eq $i 0}}
+{{end}}{{$.Text $x}}{{template "echo" $.Code $x}}{{end}}{{end}}{{/*
+*/}}{{block "vars" .}}{{range $i, $x := .Normal.Vars}}{{if eq $i 0}}
 ## var
 
-{{end}}{{$.Text $x}}{{template "echo" $.Code $x}}{{end}}{{/*
+{{end}}{{$.Text $x}}{{template "echo" $.Code $x}}{{end}}{{end}}{{/*
 
-由于未实现常规排序, 只能采取分步剔除的方法
-*/}}{{$fs:=decls $this.Decls .FUNC}}{{range $i, $x := decls $this.Decls .TYPE}}{{if eq $i 0}}
-## type
+函数
+*/}}{{block "funcs" .}}{{$g := .Normal}}{{range $i, $x := $g.Funcs}}{{if eq $i 0}}
+## func
 
-{{end}}{{$lit := identLit $x}}
-### {{$lit}}
+{{end}}
+### {{identLit $x}}
 
 {{$.Text $x}}{{template "echo" $.Code $x}}{{/*
-构造函数
-*/}}{{$pos := indexConstructor $fs $lit}}{{if ne -1 $pos}}{{$x := index $fs $pos}}{{/*
+*/}}{{range $g.ExamplesOf (anchor $x)}}{{template "example" .}}{{end}}{{end}}{{end}}{{/*
 
-*/}}{{clear $fs $pos}}{{$.Text $x}}{{template "echo" $.Code $x}}{{end}}{{/*
-成员方法
-*/}}{{range $m := methods $this.Decls $lit}}
-### {{identLit $m | starLess}}
+类型
+*/}}{{block "types" .}}{{$g := .Normal}}{{range $i, $t := $g.Types}}{{if eq $i 0}}
+## type
 
-{{$.Text $m}}{{template "echo" $.Code $m}}{{end}}{{end}}{{/*
+{{end}}
+### {{$t.Name}}
 
-函数
-*/}}{{range $i, $x := trimRight $fs}}{{if eq $i 0}}
-## func
+{{$.Text $t.Decl}}{{template "echo" $.Code $t.Decl}}{{/*
+*/}}{{range $g.ExamplesOf $t.Name}}{{template "example" .}}{{end}}{{/*
+类型常量, 变量, 构造函数
+*/}}{{range $x := $t.Consts}}{{$.Text $x}}{{template "echo" $.Code $x}}{{end}}{{/*
+*/}}{{range $x := $t.Vars}}{{$.Text $x}}{{template "echo" $.Code $x}}{{end}}{{/*
+*/}}{{range $x := $t.Funcs}}{{$.Text $x}}{{template "echo" $.Code $x}}{{/*
+*/}}{{range $g.ExamplesOf (anchor $x)}}{{template "example" .}}{{end}}{{end}}{{/*
+成员方法
+*/}}{{range $m := $t.Methods}}
+### {{identLit $m | starLess}}
 
-{{end}}{{if $x}}
-### {{identLit $x}}
+{{$.Text $m}}{{template "echo" $.Code $m}}{{/*
+*/}}{{range $g.ExamplesOf (anchor $m)}}{{template "example" .}}{{end}}{{end}}{{end}}{{end}}{{/*
+*/}}{{block "notes" .}}{{range $marker, $notes := .Package.Notes}}
+## {{noteTitle $marker}}s
 
-{{$.Text $x}}{{template "echo" $.Code $x}}{{end}}{{end}}{{/*
-*/}}{{if $x := license $this}}
+{{range $notes}} - {{.Body.Text}}{{end}}{{end}}{{end}}{{/*
+*/}}{{block "footer" .}}{{if $x := license .File}}
 # License
 
 {{wrap $x}}
-{{end}}{{end}}`
+{{end}}{{end}}{{end}}`
@@ -0,0 +1,208 @@
+package docu
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// eachDoc 依次以标识符和文档调用 fn, 包括包文档, 顶级声明, 结构体字段和接口方法的文档.
+// 包文档的标识符为 "package", 字段和接口方法形如 "Type.Field", 注释形如 "BUG(who)".
+func eachDoc(file *ast.File, fn func(ident string, doc *ast.CommentGroup)) {
+	if file.Doc != nil {
+		fn("package", file.Doc)
+	}
+	for _, node := range file.Decls {
+		switch n := node.(type) {
+		case *ast.GenDecl:
+			if n.Tok == token.IMPORT {
+				continue
+			}
+			if n.Doc != nil {
+				fn(DeclIdentLit(n), n.Doc)
+			}
+			for _, spec := range n.Specs {
+				lit := SpecIdentLit(spec)
+				if doc := SpecDoc(spec); doc != nil && doc != n.Doc {
+					fn(lit, doc)
+				}
+				ts, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				var fields *ast.FieldList
+				switch t := ts.Type.(type) {
+				case *ast.StructType:
+					fields = t.Fields
+				case *ast.InterfaceType:
+					fields = t.Methods
+				}
+				if fields == nil {
+					continue
+				}
+				for _, field := range fields.List {
+					if field.Doc == nil {
+						continue
+					}
+					name := ""
+					if len(field.Names) != 0 {
+						name = field.Names[0].Name
+					} else if ident := recvTypeIdent(field.Type); ident != nil {
+						name = ident.Name
+					}
+					fn(lit+"."+name, field.Doc)
+				}
+			}
+		case *ast.FuncDecl:
+			if n.Doc != nil {
+				fn(FuncIdentLit(n), n.Doc)
+			}
+		}
+	}
+	for _, note := range Notes(file) {
+		fn(NoteIdent(note), note)
+	}
+}
+
+// paragraphs 以空行拆分文档 text 为段落, 段落不含结尾换行.
+func paragraphs(text string) []string {
+	text = strings.Trim(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n\n")
+}
+
+// normalize 合并 s 中的连续空白为一个空格, 剔除首尾空白.
+func normalize(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Memory 为段落级翻译记忆, 记录原文段落到译文段落的对应关系.
+// 同一原文段落有多个译文时保留最先添加的.
+type Memory struct {
+	exact  map[string]string // 原文段落到译文段落
+	normal map[string]string // 规范化的原文段落到译文段落
+}
+
+// NewMemory 返回空的翻译记忆.
+func NewMemory() *Memory {
+	return &Memory{
+		exact:  make(map[string]string),
+		normal: make(map[string]string),
+	}
+}
+
+// Len 返回 m 记录的原文段落数.
+func (m *Memory) Len() int {
+	return len(m.exact)
+}
+
+// Add 按段落对应添加原文 origin 和译文 trans, 返回新增的段落数.
+// 段落数不同时无法对应, 忽略. 与原文相同的段落视作未翻译, 忽略.
+func (m *Memory) Add(origin, trans string) (n int) {
+	po, pt := paragraphs(origin), paragraphs(trans)
+	if len(po) != len(pt) {
+		return
+	}
+	for i, p := range po {
+		if p == pt[i] || normalize(p) == normalize(pt[i]) {
+			continue
+		}
+		if _, ok := m.exact[p]; !ok {
+			m.exact[p] = pt[i]
+			n++
+		}
+		if key := normalize(p); m.normal[key] == "" {
+			m.normal[key] = pt[i]
+		}
+	}
+	return
+}
+
+// AddFile 添加双语文档 file 中的全部翻译, 返回新增的段落数.
+// Godocu 风格文档以 OriginDoc 对应原文, 合并文档以 SplitComments 对应原文.
+// AddFile 调用 ClearComments 清理 file 的尾注释.
+func (m *Memory) AddFile(file *ast.File) (n int) {
+	godocu := IsGodocuFile(file)
+	if godocu {
+		ClearComments(file)
+	}
+	eachDoc(file, func(_ string, doc *ast.CommentGroup) {
+		origin, trans := SplitComments(doc.Text())
+		if origin == "" && godocu {
+			if src := OriginDoc(file.Comments, doc); src != nil {
+				origin = src.Text()
+			}
+		}
+		if origin != "" {
+			n += m.Add(origin, trans)
+		}
+	})
+	return
+}
+
+// Translate 逐段翻译文档 text, 先查找原文, 再查找规范化的原文, 未找到的段落保持原文.
+// 返回译文, 原文匹配, 规范化匹配的段落数和总段落数.
+func (m *Memory) Translate(text string) (trans string, exact, normal, total int) {
+	ps := paragraphs(text)
+	for i, p := range ps {
+		if t, ok := m.exact[p]; ok {
+			ps[i] = t
+			exact++
+		} else if t, ok := m.normal[normalize(p)]; ok {
+			ps[i] = t
+			normal++
+		}
+	}
+	if len(ps) != 0 {
+		trans = strings.Join(ps, "\n\n") + "\n"
+	}
+	return trans, exact, normal, len(ps)
+}
+
+// FillRecord 表示 Memory.Fill 预填的一个文档.
+type FillRecord struct {
+	Ident  string // 标识符, 参见 eachDoc
+	Exact  int    // 原文匹配的段落数
+	Normal int    // 规范化匹配的段落数
+	Total  int    // 总段落数
+}
+
+// Fill 以 m 预填翻译文档 file 中未翻译的文档, 返回预填记录.
+// 已有原文或已合并的文档视作已翻译. 预填文档合并为双语文档, 未匹配的段落保持原文.
+// Fill 调用 ClearComments 清理 file 的尾注释.
+func (m *Memory) Fill(file *ast.File) (records []FillRecord) {
+	godocu := IsGodocuFile(file)
+	if godocu {
+		ClearComments(file)
+	}
+	eachDoc(file, func(ident string, doc *ast.CommentGroup) {
+		text := doc.Text()
+		if strings.Contains(text, GoDocu_Dividing_line) ||
+			godocu && OriginDoc(file.Comments, doc) != nil {
+			return
+		}
+		trans, exact, normal, total := m.Translate(text)
+		if exact+normal == 0 {
+			return
+		}
+		MergeDoc(commentGroup(trans), doc)
+		records = append(records, FillRecord{ident, exact, normal, total})
+	})
+	return
+}
+
+// commentGroup 返回内容为 text 的行注释.
+func commentGroup(text string) *ast.CommentGroup {
+	cg := &ast.CommentGroup{}
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		if line == "" || line[0] == '\t' {
+			line = "//" + line
+		} else {
+			line = "// " + line
+		}
+		cg.List = append(cg.List, &ast.Comment{Text: line})
+	}
+	return cg
+}
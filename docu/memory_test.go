@@ -0,0 +1,94 @@
+package docu
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+const testMemorySource = `package io
+
+// Write writes len(p) bytes from p.
+//
+// It returns the number of bytes written.
+
+// Write 从 p 写入 len(p) 个字节.
+//
+// 它返回写入的字节数.
+func Write(p []byte) (n int, err error)
+
+// Untranslated stays as is.
+func Read(p []byte) (n int, err error)
+`
+
+const testMemoryTarget = `package bufio
+
+// WriteString writes a string.
+//
+// It returns the number of
+// bytes written.
+func WriteString(s string) (int, error)
+
+// Flush writes buffered data.
+//
+// It returns the number of bytes written.
+func Flush() (int, error)
+
+// Untranslated stays as is.
+func Reset()
+`
+
+func parseGodocu(t *testing.T, src string) *ast.File {
+	file, err := parser.ParseFile(token.NewFileSet(), "doc_zh_CN.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Index(file)
+	file.Unresolved = godocuStyle
+	return file
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	if n := m.AddFile(parseGodocu(t, testMemorySource)); n != 2 || m.Len() != 2 {
+		t.Fatalf("AddFile = %d, Len = %d, want 2", n, m.Len())
+	}
+
+	trans, exact, normal, total := m.Translate("Foo.\n\nIt returns the number of bytes written.\n")
+	if trans != "Foo.\n\n它返回写入的字节数.\n" || exact != 1 || normal != 0 || total != 2 {
+		t.Errorf("Translate = %q, %d, %d, %d", trans, exact, normal, total)
+	}
+
+	file := parseGodocu(t, testMemoryTarget)
+	records := m.Fill(file)
+	want := []FillRecord{
+		{"Flush", 1, 0, 2},
+		{"WriteString", 0, 1, 2},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("Fill = %+v, want %+v", records, want)
+	}
+	for i := range want {
+		if records[i] != want[i] {
+			t.Errorf("Fill[%d] = %+v, want %+v", i, records[i], want[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Fprint(&buf, file); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if strings.Count(out, "// 它返回写入的字节数.") != 2 ||
+		strings.Count(out, "Untranslated") != 1 {
+		t.Errorf("Fprint after Fill:\n%s", out)
+	}
+
+	// 再次预填时均已翻译
+	if records = m.Fill(file); len(records) != 0 {
+		t.Errorf("Fill again = %+v", records)
+	}
+}
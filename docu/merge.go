@@ -3,6 +3,7 @@ package docu
 import (
 	"go/ast"
 	"go/token"
+	"strings"
 )
 
 const GoDocu_Dividing_line = "___GoDocu_Dividing_line___"
@@ -12,6 +13,13 @@ const indent_GoDocu_Dividing_line = "    //" + GoDocu_Dividing_line
 // do not change this
 var comment_Dividing_line = &ast.Comment{Text: "//___GoDocu_Dividing_line___"}
 
+// isDividingLine 返回 c 是否为分割线, 包括从文件中读取的 "// ___GoDocu_Dividing_line___"
+// 以及多语言文档中带 lang 的分割线.
+func isDividingLine(c *ast.Comment) bool {
+	_, ok := dividingLang(strings.TrimPrefix(c.Text, "//"))
+	return ok
+}
+
 // MergeDeclsDoc 添加 source 与 target 中匹配的标识符文档到 target 注释底部
 // 细节:
 //    只是排版不同不会被合并
@@ -37,6 +45,10 @@ func MergeDeclsDoc(source, target []ast.Decl) {
 	sd, so = declsOf(MethodNum, source, so)
 	dd, do = declsOf(MethodNum, target, do)
 	mergeFuncDecls(sd, dd)
+
+	sd, so = declsOf(ExampleNum, source, so)
+	dd, do = declsOf(ExampleNum, target, do)
+	mergeFuncDecls(sd, dd)
 	return
 }
 
@@ -0,0 +1,222 @@
+package docu
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Model 为包文档的数据模型, 可直接编码为 JSON, 供网站, 编辑器插件等使用.
+type Model struct {
+	Name      string   // 包名
+	Import    string   // import paths
+	Canonical string   `json:",omitempty"` // 权威导入路径, 参见 CanonicalImportPaths
+	License   string   `json:",omitempty"`
+	Doc       string   `json:",omitempty"` // 包文档, 双语文档为译文
+	Origin    string   `json:",omitempty"` // 双语文档的原文
+	Imports   []string `json:",omitempty"`
+	Consts    []*Symbol
+	Vars      []*Symbol
+	Types     []*Symbol
+	Funcs     []*Symbol
+	Notes     []*Symbol `json:",omitempty"` // BUG(who) 这样的注释, 参见 Notes
+}
+
+// Symbol 表示一个声明及其文档.
+//
+// const, var 声明的 Specs 为其中的每个 ast.ValueSpec, Name 为首个标识符.
+// type 声明的 Fields 为结构体字段或接口方法, Methods 为该类型的方法.
+type Symbol struct {
+	// Kind 为 "const", "var", "type", "func", "method", "field", "embedded", "note" 之一.
+	// 接口方法的 Kind 为 "method", 嵌入字段和嵌入接口为 "embedded".
+	// note 的 Name 形如 "BUG(who)", 文档不含注释标记.
+	Kind    string
+	Name    string
+	Recv    string `json:",omitempty"` // 方法的接收者, 参见 RecvLit
+	Type    string `json:",omitempty"` // 类型字面描述, 函数和方法为 FuncLit
+	Value   string `json:",omitempty"` // const, var 的值
+	Tag     string `json:",omitempty"` // 结构体字段的 tag
+	Doc     string `json:",omitempty"` // 文档, 双语文档为译文
+	Origin  string `json:",omitempty"` // 双语文档的原文
+	Comment string `json:",omitempty"` // 尾注释
+	File    string // 源文件名, 不含路径
+	Line    int    // 源文件行号
+
+	Specs   []*Symbol `json:",omitempty"`
+	Fields  []*Symbol `json:",omitempty"`
+	Methods []*Symbol `json:",omitempty"`
+}
+
+// modeler 为生成 Model 所需的上下文.
+type modeler struct {
+	fset     *token.FileSet
+	comments []*ast.CommentGroup // 非 nil 表示 Godocu 风格文档
+}
+
+// NewModel 返回 import paths 为 key 的包文件 file 的数据模型, fset 用于计算源码位置.
+// file 通常来自 MergePackageFiles. Godocu 风格文档会调用 ClearComments.
+func NewModel(fset *token.FileSet, key string, file *ast.File) *Model {
+	m := modeler{fset: fset}
+	if IsGodocuFile(file) {
+		ClearComments(file)
+		m.comments = file.Comments
+	}
+	model := &Model{
+		Name:      file.Name.String(),
+		Import:    key,
+		Canonical: strings.Trim(CanonicalImportPaths(file), `"`),
+	}
+	model.License, _ = License(file)
+	model.Origin, model.Doc = m.doc(file.Doc)
+	for _, spec := range file.Imports {
+		if path, err := strconv.Unquote(spec.Path.Value); err == nil {
+			model.Imports = append(model.Imports, path)
+		}
+	}
+
+	named := make(map[string]*Symbol)
+	var methods []*ast.FuncDecl
+	for _, node := range file.Decls {
+		switch n := node.(type) {
+		case *ast.GenDecl:
+			switch n.Tok {
+			case token.CONST:
+				model.Consts = append(model.Consts, m.value(n))
+			case token.VAR:
+				model.Vars = append(model.Vars, m.value(n))
+			case token.TYPE:
+				for _, spec := range n.Specs {
+					s := m.typeSpec(n, spec.(*ast.TypeSpec))
+					named[s.Name] = s
+					model.Types = append(model.Types, s)
+				}
+			}
+		case *ast.FuncDecl:
+			if n.Recv == nil {
+				model.Funcs = append(model.Funcs, m.funcDecl(n))
+			} else {
+				methods = append(methods, n)
+			}
+		}
+	}
+	// 方法在其类型之后, 类型不存在时作为函数
+	for _, decl := range methods {
+		s := m.funcDecl(decl)
+		var t *Symbol
+		if ident := recvTypeIdent(decl.Recv.List[0].Type); ident != nil {
+			t = named[ident.Name]
+		}
+		if t != nil {
+			t.Methods = append(t.Methods, s)
+		} else {
+			model.Funcs = append(model.Funcs, s)
+		}
+	}
+	for _, note := range Notes(file) {
+		s := m.symbol("note", NoteIdent(note), note.Pos(), note, nil)
+		s.Origin, s.Doc = NoteBody(s.Origin), NoteBody(s.Doc)
+		model.Notes = append(model.Notes, s)
+	}
+	return model
+}
+
+// doc 返回文档 doc 的原文和译文, 非双语文档原文为空.
+func (m modeler) doc(doc *ast.CommentGroup) (origin, trans string) {
+	if doc == nil {
+		return
+	}
+	origin, trans = SplitComments(doc.Text())
+	if origin == "" && m.comments != nil {
+		origin = OriginDoc(m.comments, doc).Text()
+	}
+	return trimText(origin), trimText(trans)
+}
+
+// trimText 剔除 text 首尾的空行, 非空时以换行结尾.
+func trimText(text string) string {
+	text = strings.Trim(text, "\n")
+	if text != "" {
+		text += "\n"
+	}
+	return text
+}
+
+func (m modeler) symbol(kind, name string, pos token.Pos, doc, comment *ast.CommentGroup) *Symbol {
+	s := &Symbol{Kind: kind, Name: name, Comment: comment.Text()}
+	s.Origin, s.Doc = m.doc(doc)
+	if m.fset != nil && pos.IsValid() {
+		p := m.fset.Position(pos)
+		s.File, s.Line = filepath.Base(p.Filename), p.Line
+	}
+	return s
+}
+
+func (m modeler) value(decl *ast.GenDecl) *Symbol {
+	s := m.symbol(decl.Tok.String(), DeclIdentLit(decl), decl.Pos(), decl.Doc, nil)
+	for _, spec := range decl.Specs {
+		vs := spec.(*ast.ValueSpec)
+		doc := vs.Doc
+		if doc == decl.Doc {
+			doc = nil
+		}
+		v := m.symbol(s.Kind, IdentsLit(vs.Names), vs.Pos(), doc, vs.Comment)
+		v.Type = SpecTypeLit(vs)
+		for i, expr := range vs.Values {
+			if i != 0 {
+				v.Value += ", "
+			}
+			v.Value += types.ExprString(expr)
+		}
+		s.Specs = append(s.Specs, v)
+	}
+	return s
+}
+
+func (m modeler) typeSpec(decl *ast.GenDecl, spec *ast.TypeSpec) *Symbol {
+	doc := spec.Doc
+	if doc == nil && len(decl.Specs) == 1 {
+		doc = decl.Doc
+	}
+	s := m.symbol("type", spec.Name.String(), spec.Pos(), doc, spec.Comment)
+	s.Type = SpecTypeLit(spec)
+
+	var fields *ast.FieldList
+	kind := "field"
+	switch t := spec.Type.(type) {
+	case *ast.StructType:
+		fields = t.Fields
+	case *ast.InterfaceType:
+		fields, kind = t.Methods, "method"
+	}
+	if fields == nil {
+		return s
+	}
+	for _, field := range fields.List {
+		var f *Symbol
+		if len(field.Names) == 0 {
+			f = m.symbol("embedded", types.ExprString(field.Type), field.Pos(), field.Doc, field.Comment)
+		} else {
+			f = m.symbol(kind, IdentsLit(field.Names), field.Pos(), field.Doc, field.Comment)
+			f.Type = types.ExprString(field.Type)
+		}
+		if field.Tag != nil {
+			f.Tag = field.Tag.Value
+		}
+		s.Fields = append(s.Fields, f)
+	}
+	return s
+}
+
+func (m modeler) funcDecl(decl *ast.FuncDecl) *Symbol {
+	kind := "func"
+	if decl.Recv != nil {
+		kind = "method"
+	}
+	s := m.symbol(kind, decl.Name.String(), decl.Pos(), decl.Doc, nil)
+	s.Recv = RecvLit(decl)
+	s.Type = FuncLit(decl)
+	return s
+}
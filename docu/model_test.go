@@ -0,0 +1,86 @@
+package docu
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestNewModel(t *testing.T) {
+	const src = `// Package p is p.
+package p // import "example.com/p"
+
+import "io"
+
+// Modes.
+const (
+	// A is a.
+	A, B = 1, 2
+	C int = 3 // C is c.
+)
+
+// T is t.
+type T struct {
+	io.Reader
+	// F is f.
+	F int ` + "`json:\"f\"`" + `
+}
+
+// M does m.
+func (t *T) M(n int) error { return nil }
+
+// New returns a T.
+//
+// ___GoDocu_Dividing_line___
+//
+// New 返回 T.
+func New() *T { return nil }
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "/path/p.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Index(file)
+	m := NewModel(fset, "example.com/p", file)
+
+	if m.Name != "p" || m.Canonical != "example.com/p" || m.Doc != "Package p is p.\n" ||
+		len(m.Imports) != 1 || m.Imports[0] != "io" {
+		t.Fatalf("NewModel = %+v", m)
+	}
+	if len(m.Consts) != 1 || len(m.Consts[0].Specs) != 2 {
+		t.Fatalf("Consts = %+v", m.Consts)
+	}
+	c := m.Consts[0]
+	if c.Name != "A" || c.Doc != "Modes.\n" || c.File != "p.go" || c.Line != 7 {
+		t.Errorf("Consts[0] = %+v", c)
+	}
+	if s := c.Specs[0]; s.Name != "A, B" || s.Value != "1, 2" || s.Doc != "A is a.\n" {
+		t.Errorf("Specs[0] = %+v", s)
+	}
+	if s := c.Specs[1]; s.Type != "int" || s.Value != "3" || s.Comment != "C is c.\n" {
+		t.Errorf("Specs[1] = %+v", s)
+	}
+
+	if len(m.Types) != 1 || len(m.Types[0].Fields) != 2 || len(m.Types[0].Methods) != 1 {
+		t.Fatalf("Types = %+v", m.Types)
+	}
+	typ := m.Types[0]
+	if f := typ.Fields[0]; f.Kind != "embedded" || f.Name != "io.Reader" {
+		t.Errorf("Fields[0] = %+v", f)
+	}
+	if f := typ.Fields[1]; f.Kind != "field" || f.Type != "int" || f.Tag != "`json:\"f\"`" ||
+		f.Doc != "F is f.\n" {
+		t.Errorf("Fields[1] = %+v", f)
+	}
+	if f := typ.Methods[0]; f.Recv != "*T" || f.Type != "func (*T) M(n int) error" {
+		t.Errorf("Methods[0] = %+v", f)
+	}
+
+	if len(m.Funcs) != 1 {
+		t.Fatalf("Funcs = %+v", m.Funcs)
+	}
+	if f := m.Funcs[0]; f.Doc != "New 返回 T.\n" || f.Origin != "New returns a T.\n" {
+		t.Errorf("Funcs[0] = %+v", f)
+	}
+}
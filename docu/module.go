@@ -0,0 +1,251 @@
+package docu
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ModElem 本地文件系统中 module cache 的 "/pkg/mod/".
+const ModElem = string(os.PathSeparator) + "pkg" + string(os.PathSeparator) +
+	"mod" + string(os.PathSeparator)
+
+// Module 表示一个 Go module.
+type Module struct {
+	Dir  string // 根目录绝对路径, 通常是 go.mod 所在目录
+	Path string // module path, GOROOT/src 下为 "std"
+
+	// Require 是 go.mod 中 require 的 module path 和版本.
+	Require map[string]string
+}
+
+// ImportPath 返回 m 中绝对目录路径 abs 对应的 import paths.
+// 如果 abs 不在 m 中返回 "", false.
+func (m *Module) ImportPath(abs string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	abs = filepath.Clean(abs)
+	if abs == m.Dir {
+		if m.Path == "std" {
+			return "", true
+		}
+		return m.Path, true
+	}
+	if !strings.HasPrefix(abs, m.Dir) || abs[len(m.Dir)] != os.PathSeparator {
+		return "", false
+	}
+	rel := filepath.ToSlash(abs[len(m.Dir)+1:])
+	if m.Path == "std" {
+		return rel, true
+	}
+	return m.Path + "/" + rel, true
+}
+
+// PathDir 返回 m 中 importPath 对应的绝对目录路径. 不检查是否存在.
+// 如果 importPath 不属于 m 返回 "".
+func (m *Module) PathDir(importPath string) string {
+	if m == nil {
+		return ""
+	}
+	if m.Path == "std" {
+		return filepath.Join(m.Dir, filepath.FromSlash(importPath))
+	}
+	if importPath == m.Path {
+		return m.Dir
+	}
+	if !strings.HasPrefix(importPath, m.Path+"/") {
+		return ""
+	}
+	return filepath.Join(m.Dir, filepath.FromSlash(importPath[len(m.Path)+1:]))
+}
+
+// LookModule 从绝对目录路径 abs 开始向上查找最近的 go.mod, 返回对应的 Module.
+// module cache 中形如 path@version 的目录即使没有 go.mod 也能被识别.
+// 未找到返回 nil.
+func LookModule(abs string) *Module {
+	if abs == "" {
+		return nil
+	}
+	dir := filepath.Clean(abs)
+	for {
+		if m := readModule(dir); m != nil {
+			return m
+		}
+		if m := cacheModule(dir); m != nil {
+			return m
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return nil
+}
+
+// readModule 读取 dir 下的 go.mod, 失败返回 nil.
+func readModule(dir string) *Module {
+	fd, err := os.Open(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil
+	}
+	defer fd.Close()
+
+	m := &Module{Dir: dir}
+	block := false
+	scan := bufio.NewScanner(fd)
+	for scan.Scan() {
+		line := scan.Text()
+		if pos := strings.Index(line, "//"); pos != -1 {
+			line = line[:pos]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if block {
+			if fields[0] == ")" {
+				block = false
+			} else if len(fields) >= 2 {
+				m.require(fields[0], fields[1])
+			}
+			continue
+		}
+		switch fields[0] {
+		case "module":
+			if len(fields) >= 2 {
+				m.Path = unquote(fields[1])
+			}
+		case "require":
+			if len(fields) == 2 && fields[1] == "(" {
+				block = true
+			} else if len(fields) >= 3 {
+				m.require(fields[1], fields[2])
+			}
+		}
+	}
+	if m.Path == "" {
+		return nil
+	}
+	return m
+}
+
+func (m *Module) require(path, version string) {
+	if m.Require == nil {
+		m.Require = make(map[string]string)
+	}
+	m.Require[unquote(path)] = unquote(version)
+}
+
+func unquote(s string) string {
+	if len(s) > 1 && (s[0] == '"' || s[0] == '`') {
+		if t, err := strconv.Unquote(s); err == nil {
+			return t
+		}
+	}
+	return s
+}
+
+// cacheModule 识别 module cache 中 path@version 形式的目录 dir.
+func cacheModule(dir string) *Module {
+	base := filepath.Base(dir)
+	at := strings.LastIndexByte(base, '@')
+	if at <= 0 {
+		return nil
+	}
+	pos := strings.LastIndex(dir, ModElem)
+	if pos == -1 {
+		return nil
+	}
+	path := filepath.ToSlash(dir[pos+len(ModElem):])
+	path = path[:len(path)-len(base)+at]
+	path, ok := unescapePath(path)
+	if !ok {
+		return nil
+	}
+	return &Module{Dir: dir, Path: path}
+}
+
+// unescapePath 还原 module cache 中大写字母的转义, 比如 "!foo" 为 "Foo".
+func unescapePath(path string) (string, bool) {
+	if strings.IndexByte(path, '!') == -1 {
+		return path, true
+	}
+	buf := make([]byte, 0, len(path))
+	bang := false
+	for _, r := range path {
+		if r >= utf8.RuneSelf {
+			return "", false
+		}
+		if bang {
+			bang = false
+			if r < 'a' || r > 'z' {
+				return "", false
+			}
+			buf = append(buf, byte(r+'A'-'a'))
+			continue
+		}
+		if r == '!' {
+			bang = true
+			continue
+		}
+		if r >= 'A' && r <= 'Z' {
+			return "", false
+		}
+		buf = append(buf, byte(r))
+	}
+	return string(buf), !bang
+}
+
+// escapePath 对 path 中的大写字母进行 module cache 转义.
+func escapePath(path string) string {
+	var buf []byte
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c >= 'A' && c <= 'Z' {
+			buf = append(buf, '!', c+'a'-'A')
+		} else {
+			buf = append(buf, c)
+		}
+	}
+	return string(buf)
+}
+
+// moduleAbs 在当前工作目录所在 module 及其 require 的 module cache 中
+// 搜索 import paths 对应的绝对路径. 未找到返回 "".
+func moduleAbs(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	m := LookModule(wd)
+	if m == nil {
+		return ""
+	}
+	if dir := m.PathDir(path); dir != "" && m.Path != "std" && exists(dir) {
+		return dir
+	}
+	if GOMODCACHE == "" {
+		return ""
+	}
+	// 最长匹配
+	var mod, ver string
+	for p, v := range m.Require {
+		if (path == p || strings.HasPrefix(path, p+"/")) && len(p) > len(mod) {
+			mod, ver = p, v
+		}
+	}
+	if mod == "" {
+		return ""
+	}
+	dir := filepath.Join(GOMODCACHE, filepath.FromSlash(escapePath(mod))+"@"+escapePath(ver),
+		filepath.FromSlash(path[len(mod):]))
+	if exists(dir) {
+		return dir
+	}
+	return ""
+}
@@ -0,0 +1,62 @@
+package docu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImportPathOf(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "godocu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	mod := filepath.Join(tmp, "proj")
+	cache := filepath.Join(tmp, "pkg", "mod", "github.com", "!foo", "bar@v1.2.3")
+	docs := filepath.Join(tmp, "docs")
+	for _, dir := range []string{
+		filepath.Join(mod, "sub"),
+		filepath.Join(cache, "baz"),
+		filepath.Join(docs, "example.com", "proj"),
+	} {
+		if err = os.MkdirAll(dir, 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = ioutil.WriteFile(filepath.Join(mod, "go.mod"),
+		[]byte("module \"example.com/proj\" // comment\n\nrequire (\n\tgolang.org/x/text v0.3.0\n)\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := LookModule(filepath.Join(mod, "sub"))
+	if m == nil || m.Dir != mod || m.Path != "example.com/proj" ||
+		m.Require["golang.org/x/text"] != "v0.3.0" {
+		t.Fatalf("LookModule = %+v", m)
+	}
+	if dir := m.PathDir("example.com/proj/sub"); dir != filepath.Join(mod, "sub") {
+		t.Fatal(dir)
+	}
+
+	defer func(roots []string) { Roots = roots }(Roots)
+	Roots = []string{docs, tmp}
+
+	tests := []struct {
+		abs, want string
+		ok        bool
+	}{
+		{mod, "example.com/proj", true},
+		{filepath.Join(mod, "sub"), "example.com/proj/sub", true},
+		{filepath.Join(cache, "baz"), "github.com/Foo/bar/baz", true},
+		{filepath.Join(docs, "example.com", "proj"), "example.com/proj", true},
+		{docs, "", true},
+	}
+	for _, tt := range tests {
+		if got, ok := ImportPathOf(tt.abs); got != tt.want || ok != tt.ok {
+			t.Errorf("ImportPathOf(%q) = %q,%v want %q,%v", tt.abs, got, ok, tt.want, tt.ok)
+		}
+	}
+}
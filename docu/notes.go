@@ -0,0 +1,140 @@
+package docu
+
+import (
+	"go/ast"
+	"regexp"
+	"strings"
+)
+
+// NoteMarkers 为输出的注释标记, 比如 "BUG", "TODO", "NOTE".
+// 同 go/doc, 注释形如 "BUG(who): ...", 参见 Notes.
+var NoteMarkers = []string{"BUG"}
+
+// noteMarker 同 go/doc, 匹配注释标记和 uid.
+var noteMarker = regexp.MustCompile(`^[ \t]*([A-Z][A-Z]+)\(([^)]+)\):?`)
+
+// NoteMarker 返回注释文本 text 开头的标记和 uid, 比如 "BUG(who): ..." 的 "BUG", "who".
+// text 不是以注释标记开头时返回空.
+func NoteMarker(text string) (marker, uid string) {
+	m := noteMarker.FindStringSubmatch(text)
+	if m == nil {
+		return "", ""
+	}
+	return m[1], m[2]
+}
+
+// NoteIdent 返回注释 note 的标识符, 形如 "BUG(who)", 用于合并和翻译单元.
+func NoteIdent(note *ast.CommentGroup) string {
+	marker, uid := NoteMarker(note.Text())
+	if marker == "" {
+		return ""
+	}
+	return marker + "(" + uid + ")"
+}
+
+// NoteBody 返回剔除了注释标记的注释文本 text.
+// 双语和多语言文档中译文开头的注释标记也被剔除.
+func NoteBody(text string) string {
+	lines := strings.Split(text, "\n")
+	first := true
+	for i, line := range lines {
+		if _, ok := dividingLang(line); ok {
+			first = true
+			continue
+		}
+		if first && strings.TrimSpace(line) != "" {
+			if loc := noteMarker.FindStringIndex(line); loc != nil {
+				lines[i] = strings.TrimLeft(line[loc[1]:], " \t")
+			}
+			first = false
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isNote 返回 comment 是否以 NoteMarkers 中的注释标记开头.
+func isNote(comment *ast.CommentGroup) bool {
+	if comment == nil {
+		return false
+	}
+	marker, _ := NoteMarker(comment.Text())
+	for _, s := range NoteMarkers {
+		if marker != "" && s == marker {
+			return true
+		}
+	}
+	return false
+}
+
+// Notes 返回 file.Comments 中以 NoteMarkers 标记开头的注释, 按出现次序.
+// 注释可能相邻, 因此不以 OriginDoc 对应原文, 双语注释的原文和译文在同一个注释组中,
+// 以分割线连接, 参见 noteText.
+func Notes(file *ast.File) (notes []*ast.CommentGroup) {
+	for _, comment := range file.Comments {
+		if isNote(comment) {
+			notes = append(notes, comment)
+		}
+	}
+	return
+}
+
+// noteText 返回 Fprint 输出的注释 note 的文本.
+// 同多语言文档, 双语注释的原文和译文以分割线连接, 译文需保留注释标记.
+func noteText(note *ast.CommentGroup) string {
+	text := note.Text()
+	if HasLangs(text) {
+		return SelectLangs(text, Langs)
+	}
+	origin, trans := SplitComments(text)
+	if origin == "" || normalize(origin) == normalize(trans) {
+		return trimText(trans)
+	}
+	return JoinTranslations(origin, []Translation{{"", trans}})
+}
+
+// packageNotes 返回 files 中 go/doc 风格的注释, 以便 MergePackageFiles 保留.
+// 不区分注释标记.
+func packageNotes(files []*ast.File) (notes []*ast.CommentGroup) {
+	for _, file := range files {
+		for _, comment := range file.Comments {
+			if marker, _ := NoteMarker(comment.Text()); marker != "" {
+				notes = append(notes, comment)
+			}
+		}
+	}
+	return
+}
+
+// eachNote 以 source, target 中标识符相同的注释依次调用 fn.
+// 同一标识符的注释按出现次序对应.
+func eachNote(source, target *ast.File, fn func(source, target *ast.CommentGroup)) {
+	notes := make(map[string][]*ast.CommentGroup)
+	for _, note := range Notes(source) {
+		ident := NoteIdent(note)
+		notes[ident] = append(notes[ident], note)
+	}
+	seen := make(map[string]int)
+	for _, note := range Notes(target) {
+		ident := NoteIdent(note)
+		if i := seen[ident]; i < len(notes[ident]) {
+			fn(notes[ident][i], note)
+		}
+		seen[ident]++
+	}
+}
+
+// MergeNotes 添加 source 与 target 中匹配的注释的译文到 target 注释底部, 参见 MergeDoc.
+// 同 MergeDeclsDoc, target 通常来自源代码, source 通常是翻译文档.
+func MergeNotes(source, target *ast.File) {
+	eachNote(source, target, func(source, target *ast.CommentGroup) {
+		text := source.Text()
+		if HasLangs(text) {
+			MergeDoc(source, target)
+			return
+		}
+		origin, trans := SplitComments(text)
+		if origin != "" && normalize(origin) != normalize(trans) {
+			MergeDoc(commentGroup(trimText(trans)), target)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package docu
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestNoteMarker(t *testing.T) {
+	for _, tt := range []struct{ text, marker, uid string }{
+		{"BUG(rsc): A does not work.", "BUG", "rsc"},
+		{"  TODO(gri) B should be faster.", "TODO", "gri"},
+		{"BUG: no uid.", "", ""},
+		{"Bug(rsc): lower.", "", ""},
+	} {
+		marker, uid := NoteMarker(tt.text)
+		if marker != tt.marker || uid != tt.uid {
+			t.Errorf("NoteMarker(%q) = %q, %q", tt.text, marker, uid)
+		}
+	}
+	text := "BUG(r): B is slow.\n\n" + GoDocu_Dividing_line + "\n\nBUG(r): B 很慢.\n"
+	if s := NoteBody(text); s != "B is slow.\n\n"+GoDocu_Dividing_line+"\n\nB 很慢.\n" {
+		t.Errorf("NoteBody = %q", s)
+	}
+}
+
+func TestNotes(t *testing.T) {
+	const src = `package p
+
+// A is a.
+func A() {}
+
+// BUG(rsc): A does not work.
+
+// TODO(gri): A should be faster.
+
+// BUG(r): A is slow.
+`
+	file, err := parser.ParseFile(token.NewFileSet(), "p.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notes := Notes(file); len(notes) != 2 || NoteIdent(notes[1]) != "BUG(r)" {
+		t.Fatalf("Notes = %v", notes)
+	}
+
+	zh := parseGodocu(t, `package p
+
+// A is a.
+
+// A 是 a.
+func A()
+
+// BUG(rsc): A does not work.
+//
+// ___GoDocu_Dividing_line___
+//
+// BUG(rsc): A 无效.
+
+// BUG(r): A is slow.
+`)
+	if notes := Notes(zh); len(notes) != 2 || OriginDoc(zh.Comments, notes[1]) != nil {
+		t.Fatalf("Godocu Notes = %v", notes)
+	}
+	pkg := NewPackageDoc("p", zh)
+	if bugs := pkg.Notes["BUG"]; len(bugs) != 2 || bugs[0].UID != "rsc" ||
+		bugs[0].Body.Origin() != "A does not work.\n" ||
+		bugs[0].Body.Translation() != "A 无效.\n" ||
+		bugs[1].Body.Translation() != "" {
+		t.Errorf("PackageDoc.Notes = %v", pkg.Notes)
+	}
+
+	MergeNotes(zh, file)
+	var buf bytes.Buffer
+	if err = Fprint(&buf, file); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasSuffix(out, "// BUG(rsc): A does not work.\n//\n// "+GoDocu_Dividing_line+
+		"\n//\n// BUG(rsc): A 无效.\n\n// BUG(r): A is slow.\n\n") {
+		t.Errorf("Fprint =\n%s", out)
+	}
+
+	// 输出的注释可以再次导出, 替换
+	units := Units("p", parseGodocu(t, out))
+	if u := units[len(units)-2]; u.ID != "p BUG(rsc)" || u.Trans != "BUG(rsc): A 无效.\n" {
+		t.Errorf("Units = %+v", units)
+	}
+	target := parseGodocu(t, src)
+	Replace(target, parseGodocu(t, out))
+	if notes := Notes(target); len(notes) != 2 ||
+		noteText(notes[0]) != "BUG(rsc): A does not work.\n\n"+GoDocu_Dividing_line+"\n\nBUG(rsc): A 无效.\n" {
+		t.Errorf("Replace Notes = %q", noteText(notes[0]))
+	}
+}
@@ -0,0 +1,234 @@
+package docu
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// PackageDoc 为 go/doc 风格的包文档模型, 供模板使用, 参见 Data.Package.
+// 生成时不改变 file 的声明, 各元素的 Decl 就是 file 中的声明.
+type PackageDoc struct {
+	Name       string
+	ImportPath string
+	Doc        *Doc
+	Consts     []*ValueDoc // 未归属类型的常量
+	Vars       []*ValueDoc // 未归属类型的变量
+	Funcs      []*FuncDoc  // 非构造函数, 以及类型不在包中的方法
+	Types      []*TypeDoc
+	// Notes 为按标记分组的注释, 比如 "BUG" 对应 "BUG(who): ..." 形式的注释.
+	Notes    map[string][]*Note
+	Examples []*ExampleDoc // 包的示例
+}
+
+// ValueDoc 表示 const, var 声明.
+type ValueDoc struct {
+	Doc   *Doc
+	Names []string // 标识符, 参见 SpecNames
+	Decl  *ast.GenDecl
+}
+
+// FuncDoc 表示函数或方法声明.
+type FuncDoc struct {
+	Doc      *Doc
+	Name     string
+	Recv     string // 方法的接收者, 参见 RecvIdentLit, 函数为 ""
+	Decl     *ast.FuncDecl
+	Examples []*ExampleDoc
+}
+
+// TypeDoc 表示类型声明及归属该类型的常量, 变量, 构造函数和方法, 参见 NormalType.
+type TypeDoc struct {
+	Doc      *Doc
+	Name     string
+	Decl     *ast.GenDecl // 可能是分组声明
+	Consts   []*ValueDoc
+	Vars     []*ValueDoc
+	Funcs    []*FuncDoc // 构造函数
+	Methods  []*FuncDoc
+	Examples []*ExampleDoc
+}
+
+// ExampleDoc 表示示例函数.
+type ExampleDoc struct {
+	Doc       *Doc
+	Name      string // 所属声明的标识符, 参见 ExampleIdent
+	Suffix    string
+	Code      string // 不含 Output 注释的代码, 参见 ExampleCode
+	Output    string // "// Output:" 注释中的预期输出
+	Unordered bool   // 是否为 "// Unordered output:"
+	Decl      *ast.FuncDecl
+}
+
+// Note 表示以标记开头的注释, 比如 "BUG(who): ...".
+type Note struct {
+	UID  string // 标记后括号内的标识, 比如 who
+	Body *Doc
+}
+
+// Doc 表示声明的文档. 双语文档可分别获取原文和译文.
+type Doc struct {
+	Text   string // 同 Data.Text, 多语言文档只保留 Langs 选中的译文
+	origin string
+	trans  string
+}
+
+// Origin 返回原文, 非双语文档返回全文.
+func (d *Doc) Origin() string {
+	if d == nil {
+		return ""
+	}
+	if d.origin == "" {
+		return d.trans
+	}
+	return d.origin
+}
+
+// Translation 返回译文, 非双语文档返回 "".
+// 多语言文档返回 Langs 选中的首个译文, 参见 SplitComments.
+func (d *Doc) Translation() string {
+	if d == nil || d.origin == "" {
+		return ""
+	}
+	return d.trans
+}
+
+// Deprecated 返回 d 是否已废弃, 参见 IsDeprecated.
+func (d *Doc) Deprecated() bool {
+	return d != nil && (IsDeprecated(d.origin) || IsDeprecated(d.trans))
+}
+
+// String 返回 d.Text.
+func (d *Doc) String() string {
+	if d == nil {
+		return ""
+	}
+	return d.Text
+}
+
+// NewPackageDoc 返回 import paths 为 key 的包文件 file 的文档模型.
+// file 通常来自 MergePackageFiles, 声明的次序参见 GroupNormal.
+// Godocu 风格文档需要先调用 ClearComments, 以便计算原文.
+func NewPackageDoc(key string, file *ast.File) *PackageDoc {
+	m := modeler{}
+	if IsGodocuFile(file) {
+		m.comments = file.Comments
+	}
+	n := GroupNormal(file.Decls)
+	pkg := &PackageDoc{
+		Name:       file.Name.String(),
+		ImportPath: key,
+		Doc:        m.docOf(file.Doc),
+		Consts:     m.values(n.Consts),
+		Vars:       m.values(n.Vars),
+		Funcs:      m.funcs(n, n.Funcs),
+		Examples:   m.examples(n, ""),
+	}
+	for _, note := range Notes(file) {
+		if pkg.Notes == nil {
+			pkg.Notes = make(map[string][]*Note)
+		}
+		marker, uid := NoteMarker(note.Text())
+		pkg.Notes[marker] = append(pkg.Notes[marker], &Note{UID: uid, Body: m.noteDoc(note)})
+	}
+	for _, t := range n.Types {
+		pkg.Types = append(pkg.Types, &TypeDoc{
+			Doc:      m.docOf(t.Decl.Doc),
+			Name:     t.Name(),
+			Decl:     t.Decl,
+			Consts:   m.values(t.Consts),
+			Vars:     m.values(t.Vars),
+			Funcs:    m.funcs(n, t.Funcs),
+			Methods:  m.funcs(n, t.Methods),
+			Examples: m.examples(n, t.Name()),
+		})
+	}
+	return pkg
+}
+
+// docOf 返回 doc 的 Doc, doc 为 nil 时返回空 Doc.
+func (m modeler) docOf(doc *ast.CommentGroup) *Doc {
+	if doc == nil {
+		return new(Doc)
+	}
+	d := &Doc{Text: SelectLangs(doc.Text(), Langs)}
+	d.origin, d.trans = m.doc(doc)
+	return d
+}
+
+// noteDoc 返回注释 note 剔除注释标记的 Doc.
+func (m modeler) noteDoc(note *ast.CommentGroup) *Doc {
+	d := m.docOf(note)
+	d.Text, d.origin, d.trans = NoteBody(d.Text), NoteBody(d.origin), NoteBody(d.trans)
+	return d
+}
+
+func (m modeler) values(decls []ast.Decl) (values []*ValueDoc) {
+	for _, decl := range decls {
+		decl := decl.(*ast.GenDecl)
+		values = append(values, &ValueDoc{
+			Doc:   m.docOf(decl.Doc),
+			Names: SpecNames(decl),
+			Decl:  decl,
+		})
+	}
+	return
+}
+
+func (m modeler) funcs(n *Normal, decls []ast.Decl) (funcs []*FuncDoc) {
+	for _, decl := range decls {
+		decl := decl.(*ast.FuncDecl)
+		f := &FuncDoc{
+			Doc:      m.docOf(decl.Doc),
+			Name:     decl.Name.Name,
+			Decl:     decl,
+			Examples: m.examples(n, Anchor(decl)),
+		}
+		if decl.Recv != nil {
+			f.Recv = RecvIdentLit(decl)
+		}
+		funcs = append(funcs, f)
+	}
+	return
+}
+
+func (m modeler) examples(n *Normal, ident string) (examples []*ExampleDoc) {
+	for _, fn := range n.ExamplesOf(ident) {
+		e := &ExampleDoc{
+			Doc:    m.docOf(fn.Doc),
+			Name:   ident,
+			Suffix: ExampleSuffix(fn),
+			Decl:   fn,
+		}
+		e.Code, e.Output, e.Unordered = splitOutput(ExampleCode(fn))
+		examples = append(examples, e)
+	}
+	return
+}
+
+// splitOutput 同 go test, 分割示例代码 code 末尾的 "// Output:" 注释.
+func splitOutput(code string) (_, output string, unordered bool) {
+	lines := strings.Split(code, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(line, "//") {
+			break
+		}
+		line = strings.TrimSpace(line[2:])
+		lower := strings.ToLower(line)
+		unordered = strings.HasPrefix(lower, "unordered output:")
+		if !unordered && !strings.HasPrefix(lower, "output:") {
+			continue
+		}
+		out := []string{strings.TrimSpace(line[strings.IndexByte(line, ':')+1:])}
+		for _, line := range lines[i+1:] {
+			line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "//"))
+			out = append(out, line)
+		}
+		output = strings.Trim(strings.Join(out, "\n"), "\n")
+		if output != "" {
+			output += "\n"
+		}
+		return strings.TrimRight(strings.Join(lines[:i], "\n"), "\n"), output, unordered
+	}
+	return code, "", false
+}
@@ -0,0 +1,103 @@
+package docu
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestNewPackageDoc(t *testing.T) {
+	const src = `// Package p is p.
+package p
+
+// Max is max.
+const Max = 1
+
+// Mode is mode.
+type Mode int
+
+// Modes.
+const (
+	A Mode = iota
+	B
+)
+
+// New returns a T.
+//
+// ___GoDocu_Dividing_line___
+//
+// New 返回 T.
+func New() *T { return nil }
+
+// T is t.
+type T struct{}
+
+// M does m.
+func (t *T) M() {}
+
+// F is f.
+func F() {}
+
+func ExampleT_M() {
+	var t T
+	t.M()
+	// Output:
+	// m
+}
+
+func Example_second() {
+	// unordered output: a
+	// b
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	textExamples(fset, file)
+	Index(file)
+	decls := append(file.Decls[:0:0], file.Decls...)
+	pkg := NewPackageDoc("example.com/p", file)
+
+	for i, decl := range decls {
+		if file.Decls[i] != decl {
+			t.Fatal("NewPackageDoc changed file.Decls")
+		}
+	}
+	if pkg.Name != "p" || pkg.ImportPath != "example.com/p" ||
+		pkg.Doc.Text != "Package p is p.\n" || pkg.Doc.Origin() != pkg.Doc.Text ||
+		pkg.Doc.Translation() != "" {
+		t.Fatalf("NewPackageDoc = %+v", pkg)
+	}
+	if len(pkg.Consts) != 1 || pkg.Consts[0].Names[0] != "Max" ||
+		len(pkg.Funcs) != 1 || pkg.Funcs[0].Name != "F" || len(pkg.Types) != 2 {
+		t.Fatalf("NewPackageDoc = %+v", pkg)
+	}
+
+	mode, typ := pkg.Types[0], pkg.Types[1]
+	if mode.Name != "Mode" || len(mode.Consts) != 1 || mode.Consts[0].Doc.Text != "Modes.\n" {
+		t.Errorf("Types[0] = %+v", mode)
+	}
+	if typ.Name != "T" || len(typ.Funcs) != 1 || len(typ.Methods) != 1 {
+		t.Fatalf("Types[1] = %+v", typ)
+	}
+	if d := typ.Funcs[0].Doc; d.Origin() != "New returns a T.\n" || d.Translation() != "New 返回 T.\n" {
+		t.Errorf("New.Doc = %q, %q", d.Origin(), d.Translation())
+	}
+	m := typ.Methods[0]
+	if m.Recv != "*T" || len(m.Examples) != 1 {
+		t.Fatalf("Methods[0] = %+v", m)
+	}
+	if e := m.Examples[0]; e.Name != "T.M" || e.Code != "var t T\nt.M()" ||
+		e.Output != "m\n" || e.Unordered {
+		t.Errorf("ExampleT_M = %+v", e)
+	}
+	if len(pkg.Examples) != 1 {
+		t.Fatalf("Examples = %+v", pkg.Examples)
+	}
+	if e := pkg.Examples[0]; e.Suffix != "second" || e.Code != "" ||
+		e.Output != "a\nb\n" || !e.Unordered {
+		t.Errorf("Example_second = %+v", e)
+	}
+}
@@ -28,12 +28,12 @@ func Resultsify(results string) string {
 	return " (" + results + ")"
 }
 
-var prefix = []string{"// ", "\t// ", "\t\t// "}
-var indents = []string{"", "\xff\t\xff", "\xff\t\t\xff"}
-var rawindents = []string{"", "\t", "\t\t"}
+var prefix = []string{"// ", "\t// ", "\t\t// ", "\t\t\t// ", "\t\t\t\t// "}
+var indents = []string{"", "\xff\t\xff", "\xff\t\t\xff", "\xff\t\t\t\xff", "\xff\t\t\t\t\xff"}
+var rawindents = []string{"", "\t", "\t\t", "\t\t\t", "\t\t\t\t"}
 
 // Format 调用 LineWrapper 换行格式化注释 doc 输出到 output.
-// indent 是 "\t" 缩进个数, 值范围为 0,1,2.
+// indent 是 "\t" 缩进个数, 值范围为 0-4.
 // 如果 doc 是合并文档, 包含 GoDocu_Dividing_line, 表示输出双语文档.
 // 如果 doc 非双语文档且 comments 非 nil, 则在 comments 中查找并输出 OriginDoc.
 func Format(output io.Writer, indent int,
@@ -41,7 +41,12 @@ func Format(output io.Writer, indent int,
 	if doc == nil {
 		return
 	}
-	source, text := SplitComments(doc.Text())
+	var source, text string
+	if text = doc.Text(); HasLangs(text) {
+		source, text = formatLangs(text)
+	} else {
+		source, text = SplitComments(text)
+	}
 	if source == "" && comments != nil {
 		source = OriginDoc(comments, doc).Text()
 	}
@@ -127,7 +132,15 @@ func wrappedBefor(text string, limit int) int {
 
 // SplitComments 以 GoDocu_Dividing_line 分割 text 为两部分.
 // 如果没有分割线返回 "",text
+// 多语言文档返回原文和 Langs 选中的首个译文, 参见 SplitTranslations.
 func SplitComments(text string) (string, string) {
+	if HasLangs(text) {
+		origin, trans := SplitTranslations(text)
+		if trans = SelectTranslations(trans, Langs); len(trans) == 0 {
+			return origin, ""
+		}
+		return origin, trans[0].Text
+	}
 	n := strings.Index(text, GoDocu_Dividing_line)
 	if n == -1 {
 		return "", text
@@ -350,16 +363,30 @@ func Fprint(output io.Writer, file *ast.File) (err error) {
 		text += ` // ` + imp
 	}
 
-	err = fprint(output, "package ", text, nl+nl)
+	notes := Notes(file)
+	last := len(file.Decls) - 1
+	for last >= 0 && !isPrintDecl(file.Decls[last]) {
+		last--
+	}
+	// gofmt 风格文件末尾没有空行
+	sep := nl
+	if Gofmt && last == -1 && len(notes) == 0 {
+		sep = ""
+	}
 
-	if err == nil && len(file.Imports) != 0 {
-		err = fprint(output, ImportsString(file.Imports), nl)
+	if len(file.Imports) == 0 {
+		err = fprint(output, "package ", text, nl, sep)
+	} else {
+		err = fprint(output, "package ", text, nl+nl)
+		if err == nil {
+			err = fprint(output, ImportsString(file.Imports), sep)
+		}
 	}
 
 	if err != nil {
 		return
 	}
-	for _, node := range file.Decls {
+	for i, node := range file.Decls {
 		switch n := node.(type) {
 		case *ast.GenDecl:
 			switch n.Tok {
@@ -373,20 +400,43 @@ func Fprint(output io.Writer, file *ast.File) (err error) {
 		case *ast.FuncDecl:
 			err = FprintFuncDecl(output, n, comments)
 		}
-		if err == nil {
+		if err == nil && (!Gofmt || i != last || len(notes) != 0) {
 			err = fprint(output, nl)
 		}
 		if err != nil {
 			break
 		}
 	}
+	// BUG(who) 这样的注释在最后
+	for i, note := range notes {
+		if err == nil {
+			err = fprint(output, WrapComments(noteText(note), "// ", 77))
+		}
+		if err == nil && (!Gofmt || i != len(notes)-1) {
+			err = fprint(output, nl)
+		}
+	}
 	return
 }
 
+// isPrintDecl 返回 Fprint 是否输出 decl.
+func isPrintDecl(decl ast.Decl) bool {
+	switch n := decl.(type) {
+	case *ast.GenDecl:
+		return n.Tok != token.IMPORT
+	case *ast.FuncDecl:
+		return true
+	}
+	return false
+}
+
 // FprintFuncDecl 向 w 输出顶级函数声明 fn. comments 用于输出双语文档.
+// 示例函数含函数体.
 func FprintFuncDecl(w io.Writer, fn *ast.FuncDecl, comments []*ast.CommentGroup) (err error) {
 	err = Format(w, 0, fn.Doc, comments)
-	if err == nil {
+	if err == nil && isTextExample(fn) {
+		err = fprint(w, MethodLit(fn), " ", exampleBlock(fn), nl)
+	} else if err == nil {
 		err = fprint(w, MethodLit(fn), nl)
 	}
 	return
@@ -422,7 +472,12 @@ func FprintGenDecl(w io.Writer, decl *ast.GenDecl, comments []*ast.CommentGroup)
 	}
 
 	out := false
-	for _, spec := range decl.Specs {
+	specs := decl.Specs
+	if Gofmt && err == nil {
+		err = gofmtGenDecl(tw, indent, decl, comments)
+		specs, out = nil, true
+	}
+	for _, spec := range specs {
 		if spec == nil {
 			continue
 		}
@@ -475,6 +530,10 @@ func NewWriter(w io.Writer) *tabwriter.Writer {
 func FprintValueSpec(w *tabwriter.Writer, indent int,
 	vs *ast.ValueSpec, comments []*ast.CommentGroup) (err error) {
 
+	if Gofmt {
+		_, err = gofmtValueSpec(w, indent, vs, false, comments)
+		return
+	}
 	if err = Format(w, indent, vs.Doc, comments); err == nil {
 		err = fprint(w, indents[indent], IdentsLit(vs.Names))
 	}
@@ -522,8 +581,12 @@ func FprintValueSpec(w *tabwriter.Writer, indent int,
 func FprintTypeSpec(w *tabwriter.Writer, indent int,
 	ts *ast.TypeSpec, comments []*ast.CommentGroup) (err error) {
 
+	if Gofmt {
+		_, err = gofmtTypeSpec(w, indent, ts, false, comments)
+		return
+	}
 	if err = Format(w, indent, ts.Doc, comments); err == nil {
-		err = fprint(w, indents[indent], ts.Name.String())
+		err = fprint(w, indents[indent], ts.Name.String(), TypeParamsLit(ts.TypeParams))
 	}
 	if err != nil {
 		return
@@ -560,6 +623,9 @@ func FprintTypeSpec(w *tabwriter.Writer, indent int,
 
 // FprintFieldList 向 w 输出 fields. indent 是 tab 缩进个数, comments 用于输出双语文档.
 func FprintFieldList(w *tabwriter.Writer, indent int, fields *ast.FieldList, comments []*ast.CommentGroup) (err error) {
+	if Gofmt {
+		return gofmtFieldList(w, indent, fields, comments)
+	}
 	for i, field := range fields.List {
 		if field.Doc != nil {
 			// 注释前加换行
@@ -614,11 +680,13 @@ func FprintMethods(w *tabwriter.Writer, indent int, methods *ast.FieldList, comm
 			if lit != "" && (len(ftyp.Results.List) > 1 ||
 				len(ftyp.Results.List[0].Names) != 0) {
 				lit = " (" + lit + ")"
+			} else if lit != "" {
+				lit = " " + lit
 			}
 			fprint(w, indents[indent], field.Names[0].String(),
 				"("+FieldListLit(ftyp.Params)+")", lit)
 		} else {
-			// embedded interface
+			// embedded interface 或类型集合, 比如 ~int | ~string
 			fprint(w, indents[indent], types.ExprString(field.Type))
 		}
 
@@ -37,6 +37,14 @@ func Replace(target, source *ast.File) {
 	sd, so = declsOf(MethodNum, source.Decls, so)
 	dd, do = declsOf(MethodNum, target.Decls, do)
 	replaceFuncDecls(target, source, dd, sd)
+
+	sd, so = declsOf(ExampleNum, source.Decls, so)
+	dd, do = declsOf(ExampleNum, target.Decls, do)
+	replaceFuncDecls(target, source, dd, sd)
+
+	eachNote(source, target, func(sdoc, tdoc *ast.CommentGroup) {
+		replaceNote(target, source, tdoc, sdoc)
+	})
 	return
 }
 
@@ -145,7 +153,25 @@ func replaceComment(target, source *ast.CommentGroup) {
 func replaceDoc(dst, src *ast.File, target, source *ast.CommentGroup) {
 	// source 必须是翻译, 且 target 无 origin 才能替换
 	// 其实是合并
-	if target == nil || OriginDoc(src.Comments, source) == nil {
+	if target == nil {
+		return
+	}
+	// 多语言文档之间补充 target 缺少的译文
+	if text := source.Text(); HasLangs(text) && HasLangs(target.Text()) {
+		have := make(map[string]bool)
+		_, trans := SplitTranslations(target.Text())
+		for _, t := range trans {
+			have[t.Lang] = true
+		}
+		_, trans = SplitTranslations(text)
+		for _, t := range SelectTranslations(trans, Langs) {
+			if t.Lang != "" && !have[t.Lang] {
+				AddTranslation(target, t.Lang, t.Text)
+			}
+		}
+		return
+	}
+	if OriginDoc(src.Comments, source) == nil {
 		return
 	}
 
@@ -154,6 +180,21 @@ func replaceDoc(dst, src *ast.File, target, source *ast.CommentGroup) {
 	}
 }
 
+// replaceNote 负责 Notes, 注释的原文和译文在同一个注释组中.
+func replaceNote(dst, src *ast.File, target, source *ast.CommentGroup) {
+	text := source.Text()
+	if HasLangs(text) && HasLangs(target.Text()) {
+		replaceDoc(dst, src, target, source)
+		return
+	}
+	if origin, _ := SplitComments(text); origin == "" {
+		return
+	}
+	if origin, _ := SplitComments(target.Text()); origin == "" {
+		ReplaceDoc(target, source)
+	}
+}
+
 // ReplaceDoc 替换 target.List 为 source.list.
 // 保持 target.Pos(), target.End() 不变
 func ReplaceDoc(target, source *ast.CommentGroup) {
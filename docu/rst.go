@@ -0,0 +1,69 @@
+package docu
+
+// RSTTemplate 为内置的 reStructuredText 模板.
+const RSTTemplate = `{{define "code"}}
+.. code-block:: go
+
+{{indent "    " (trim .)}}
+{{end}}{{define "doc"}}{{if .}}
+{{literalDoc . "::\n" ""}}{{end}}{{end}}{{define "title"}}
+{{.}}
+{{underline "~" .}}
+{{end}}{{define "example"}}
+.. rubric:: Example{{if $s := exampleSuffix .}} ({{$s}}){{end}}
+{{template "doc" (selectLangs .Doc.Text)}}{{template "code" exampleCode .}}{{end}}{{/*
+此模板输出 reStructuredText 格式, 文档中的预格式化文本输出为文字块.
+各部分以 block 定义, 执行数据都是 Data, 可用 define 单独覆盖:
+header, consts, vars, funcs, types, notes, footer.
+示例函数以 example 输出, 方法 ExamplesOf 返回属于某个声明的示例.
+*/}}{{if eq .Key .ImportPath}}{{$.Type "rst"}}{{block "header" .}}{{$this := .File}}{{/*
+*/}}{{$title := base .ImportPath}}{{$title}}
+{{underline "=" $title}}
+{{template "code" (printf "import %q" .ImportPath)}}{{/*
+*/}}{{if $trans := progress $this}}
+Translation Progress: {{$trans}}%
+{{end}}{{if $x := canonicalImportPaths $this}}{{template "code" $x}}{{end}}{{/*
+*/}}{{if $this.Doc}}{{template "doc" (selectLangs $this.Doc.Text)}}{{end}}{{/*
+*/}}{{range .Normal.ExamplesOf ""}}{{template "example" .}}{{end}}{{end}}{{/*
+
+常量, 变量
+*/}}{{block "consts" .}}{{range $i, $x := .Normal.Consts}}{{if eq $i 0}}
+Constants
+---------
+{{end}}{{template "code" $.Code $x}}{{template "doc" $.Text $x}}{{end}}{{end}}{{/*
+*/}}{{block "vars" .}}{{range $i, $x := .Normal.Vars}}{{if eq $i 0}}
+Variables
+---------
+{{end}}{{template "code" $.Code $x}}{{template "doc" $.Text $x}}{{end}}{{end}}{{/*
+
+函数
+*/}}{{block "funcs" .}}{{$g := .Normal}}{{range $i, $x := $g.Funcs}}{{if eq $i 0}}
+Functions
+---------
+{{end}}{{template "title" identLit $x}}{{template "code" $.Code $x}}{{template "doc" $.Text $x}}{{/*
+*/}}{{range $g.ExamplesOf (anchor $x)}}{{template "example" .}}{{end}}{{end}}{{end}}{{/*
+
+类型
+*/}}{{block "types" .}}{{$g := .Normal}}{{range $i, $t := $g.Types}}{{if eq $i 0}}
+Types
+-----
+{{end}}{{template "title" $t.Name}}{{template "code" $.Code $t.Decl}}{{template "doc" $.Text $t.Decl}}{{/*
+*/}}{{range $g.ExamplesOf $t.Name}}{{template "example" .}}{{end}}{{/*
+类型常量, 变量, 构造函数
+*/}}{{range $x := $t.Consts}}{{template "code" $.Code $x}}{{template "doc" $.Text $x}}{{end}}{{/*
+*/}}{{range $x := $t.Vars}}{{template "code" $.Code $x}}{{template "doc" $.Text $x}}{{end}}{{/*
+*/}}{{range $x := $t.Funcs}}{{template "code" $.Code $x}}{{template "doc" $.Text $x}}{{/*
+*/}}{{range $g.ExamplesOf (anchor $x)}}{{template "example" .}}{{end}}{{end}}{{/*
+成员方法
+*/}}{{range $m := $t.Methods}}{{template "title" (identLit $m | starLess)}}{{/*
+*/}}{{template "code" $.Code $m}}{{template "doc" $.Text $m}}{{/*
+*/}}{{range $g.ExamplesOf (anchor $m)}}{{template "example" .}}{{end}}{{end}}{{end}}{{end}}{{/*
+*/}}{{block "notes" .}}{{range $marker, $notes := .Package.Notes}}{{$title := printf "%ss" (noteTitle $marker)}}
+{{$title}}
+{{underline "-" $title}}
+{{range $notes}}{{template "doc" .Body.Text}}{{end}}{{end}}{{end}}{{/*
+*/}}{{block "footer" .}}{{if $x := license .File}}
+License
+-------
+
+{{literalDoc $x "" ""}}{{end}}{{end}}{{end}}`
@@ -0,0 +1,120 @@
+package docu
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// GoDocu_Stale_line 为过期翻译的标记, 独占译文的第一段.
+// 翻译者更新译文后应删除该标记.
+const GoDocu_Stale_line = "___GoDocu_Stale___"
+
+// StaleRecord 表示一个过期的翻译, 即原文已变更但译文未更新.
+type StaleRecord struct {
+	Package   string // 包名, 调用者可替换为 import paths
+	Ident     string // 标识符, 包文档为 "package", 字段形如 "Type.Field"
+	OldOrigin string // 翻译文档中保存的原文
+	NewOrigin string // 源码中的原文
+}
+
+// IsStale 返回文档 doc 的译文是否具有过期标记.
+func IsStale(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	_, text := SplitComments(doc.Text())
+	return strings.HasPrefix(text, GoDocu_Stale_line+"\n")
+}
+
+// markStale 在 doc 的译文之前插入过期标记, 保持 doc.Pos() 不变.
+// 多语言文档标记 SplitComments 选用的译文.
+func markStale(doc *ast.CommentGroup) {
+	if IsStale(doc) {
+		return
+	}
+	i, ok := translationStart(doc)
+	if !ok || i == len(doc.List) {
+		return
+	}
+	slash := doc.List[i].Slash
+	list := make([]*ast.Comment, 0, len(doc.List)+2)
+	list = append(list, doc.List[:i]...)
+	list = append(list,
+		&ast.Comment{Slash: slash, Text: "// " + GoDocu_Stale_line},
+		&ast.Comment{Slash: slash, Text: "//"})
+	doc.List = append(list, doc.List[i:]...)
+}
+
+// docsOf 返回 file 中标识符到文档的映射, 同名的文档按出现次序排列.
+func docsOf(file *ast.File) map[string][]*ast.CommentGroup {
+	docs := make(map[string][]*ast.CommentGroup)
+	eachDoc(file, func(ident string, doc *ast.CommentGroup) {
+		docs[ident] = append(docs[ident], doc)
+	})
+	return docs
+}
+
+// eachStale 以过期翻译的标识符, 翻译文档和源码文档依次调用 fn.
+// 翻译文档中保存的原文与源码文档不同, 或者译文具有过期标记的视作过期, 忽略排版差异.
+func eachStale(source, target *ast.File,
+	fn func(ident, old string, doc, src *ast.CommentGroup)) {
+
+	godocu := IsGodocuFile(target)
+	if godocu {
+		ClearComments(target)
+	}
+	docs := docsOf(source)
+	seen := make(map[string]int)
+	eachDoc(target, func(ident string, doc *ast.CommentGroup) {
+		n := seen[ident]
+		seen[ident]++
+		if n >= len(docs[ident]) {
+			return
+		}
+		src := docs[ident][n]
+
+		origin, trans := SplitComments(doc.Text())
+		if origin == "" && godocu {
+			if o := OriginDoc(target.Comments, doc); o != nil {
+				origin = o.Text()
+			}
+		}
+		if origin == "" || normalize(origin) == normalize(trans) {
+			// 未翻译
+			return
+		}
+		if IsStale(doc) || normalize(origin) != normalize(src.Text()) {
+			fn(ident, origin, doc, src)
+		}
+	})
+}
+
+// Stales 对比翻译文档 target 中保存的原文和源码 source 的文档, 返回过期的翻译.
+// target 应该是 Godocu 风格或合并的双语文档, 会调用 ClearComments.
+func Stales(source, target *ast.File) (records []StaleRecord) {
+	pkg := source.Name.String()
+	eachStale(source, target, func(ident, old string, _, src *ast.CommentGroup) {
+		records = append(records, StaleRecord{pkg, ident, old, src.Text()})
+	})
+	return
+}
+
+// MarkStales 为翻译文档 target 中过期的译文添加过期标记, 返回过期翻译的个数.
+// 应在以 MergeDeclsDoc 合并之前调用, 参见 Stales.
+func MarkStales(source, target *ast.File) (n int) {
+	eachStale(source, target, func(_, _ string, doc, _ *ast.CommentGroup) {
+		markStale(doc)
+		n++
+	})
+	return
+}
+
+// StaleCount 返回文档 file 中具有过期标记的译文个数.
+func StaleCount(file *ast.File) (n int) {
+	eachDoc(file, func(_ string, doc *ast.CommentGroup) {
+		if IsStale(doc) {
+			n++
+		}
+	})
+	return
+}
@@ -0,0 +1,137 @@
+package docu
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestStales(t *testing.T) {
+	const src = `package p
+
+// A returns an initialized list.
+func A()
+
+// B does b.
+func B()
+
+// C does c.
+func C()
+`
+	const dst = `package p
+
+// A returns a new list.
+
+// A 返回新链表.
+func A()
+
+// B does b.
+
+// B 做 b.
+func B()
+
+// C does c.
+func C()
+`
+	source, err := parser.ParseFile(token.NewFileSet(), "src.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Index(source)
+
+	records := Stales(source, parseGodocu(t, dst))
+	want := StaleRecord{"p", "A", "A returns a new list.\n", "A returns an initialized list.\n"}
+	if len(records) != 1 || records[0] != want {
+		t.Fatalf("Stales = %+v, want %+v", records, want)
+	}
+
+	target := parseGodocu(t, dst)
+	if n := MarkStales(source, target); n != 1 {
+		t.Fatalf("MarkStales = %d, want 1", n)
+	}
+	if n := StaleCount(target); n != 1 {
+		t.Errorf("StaleCount = %d, want 1", n)
+	}
+	// 已标记的译文总是过期的
+	if n := MarkStales(source, target); n != 1 || StaleCount(target) != 1 {
+		t.Errorf("MarkStales again = %d, StaleCount = %d", n, StaleCount(target))
+	}
+
+	MergeDeclsDoc(target.Decls, source.Decls)
+	doc := source.Decls[0].(*ast.FuncDecl).Doc
+	if !IsStale(doc) {
+		t.Errorf("merged doc is not stale:\n%s", doc.Text())
+	}
+	if text := doc.Text(); text != "A returns an initialized list.\n"+
+		GoDocu_Dividing_line+"\n"+GoDocu_Stale_line+"\n\nA 返回新链表.\n" {
+		t.Errorf("merged doc:\n%s", text)
+	}
+}
+
+func TestMarkStaleMerged(t *testing.T) {
+	const src = `package p
+
+// A returns an initialized list.
+func A()
+`
+	const dst = `package p
+
+// A returns a new list.
+//
+// ___GoDocu_Dividing_line___
+//
+// A 返回新链表.
+func A()
+`
+	source, err := parser.ParseFile(token.NewFileSet(), "src.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := parseGodocu(t, dst)
+	if n := MarkStales(source, target); n != 1 {
+		t.Fatalf("MarkStales = %d, want 1", n)
+	}
+	doc := target.Decls[0].(*ast.FuncDecl).Doc
+	if !IsStale(doc) || !strings.HasPrefix(doc.Text(), "A returns a new list.\n") {
+		t.Errorf("marked doc:\n%s", doc.Text())
+	}
+}
+
+func TestMarkStaleLangs(t *testing.T) {
+	const src = `package p
+
+// A returns an initialized list.
+func A()
+`
+	const dst = `package p
+
+// A returns a new list.
+//
+// ___GoDocu_Dividing_line___ zh_CN
+//
+// A 返回新链表.
+//
+// ___GoDocu_Dividing_line___ zh_TW
+//
+// A 返回新鏈表.
+func A()
+`
+	source, err := parser.ParseFile(token.NewFileSet(), "src.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Langs = []string{"zh_TW"}
+	defer func() { Langs = nil }()
+	target := parseGodocu(t, dst)
+	MarkStales(source, target)
+	MarkStales(source, target)
+	doc := target.Decls[0].(*ast.FuncDecl).Doc
+	origin, trans := SplitTranslations(doc.Text())
+	if !IsStale(doc) || StaleCount(target) != 1 || origin != "A returns a new list.\n" ||
+		len(trans) != 2 || trans[0].Text != "A 返回新链表.\n" ||
+		trans[1].Text != GoDocu_Stale_line+"\n\nA 返回新鏈表.\n" {
+		t.Errorf("marked doc:\n%s", doc.Text())
+	}
+}
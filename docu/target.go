@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"go/ast"
+	"go/build/constraint"
 	"io"
 	"io/ioutil"
 	"os"
@@ -29,32 +30,50 @@ func IsNormalName(name string) bool {
 }
 
 var declPackage = []byte("\npackage ")
-var plusBuild = []byte("+build")
-var linux = []byte("linux")
-var slashslash = []byte("//")
 
-func buildForLinux(code []byte) bool {
+// buildFor 返回 code 的构建约束在标签判定 ok 下是否成立.
+// 存在 //go:build 时只使用它, 否则所有 +build 行为 AND 关系.
+// 约束无效时返回 false.
+func buildFor(code []byte, ok func(tag string) bool) bool {
 	pos := bytes.Index(code, declPackage)
 	if pos == -1 {
 		return bytes.HasPrefix(code, declPackage[1:])
 	}
 	code = code[:pos+1]
 
+	var goBuild constraint.Expr
+	var plusBuild []constraint.Expr
 	for len(code) != 0 {
 		pos = bytes.IndexByte(code, '\n')
-		line := code[:pos]
+		line := string(bytes.TrimSpace(code[:pos]))
 		code = code[pos+1:]
-		if !bytes.HasPrefix(line, slashslash) {
+		if !strings.HasPrefix(line, "//") {
 			continue
 		}
-		line = bytes.TrimSpace(line[2:])
-		if !bytes.HasPrefix(line, plusBuild) {
+		isGo := constraint.IsGoBuild(line)
+		if !isGo && !constraint.IsPlusBuild(line) {
 			continue
 		}
-		line = line[len(plusBuild):]
-		pos = bytes.Index(line, linux)
-		return pos > 0 && line[pos-1] == ' ' &&
-			(pos+len(linux) == len(line) || line[pos+len(linux)] == ' ')
+		x, err := constraint.Parse(line)
+		if err != nil {
+			return false
+		}
+		if isGo {
+			if goBuild != nil {
+				return false
+			}
+			goBuild = x
+		} else {
+			plusBuild = append(plusBuild, x)
+		}
+	}
+	if goBuild != nil {
+		return goBuild.Eval(ok)
+	}
+	for _, x := range plusBuild {
+		if !x.Eval(ok) {
+			return false
+		}
 	}
 	return true
 }
@@ -171,32 +190,63 @@ func LookReadme(path string) string {
 
 // LookImportPath 返回绝对目录路径 abs 中的 import paths 值. 未找到返回 ""
 func LookImportPath(abs string) string {
+	importPaths, _ := ImportPathOf(abs)
+	return importPaths
+}
+
+// ImportPathOf 返回绝对目录路径 abs 中的 import paths 值, 以及是否识别成功.
+// 依次按照 Roots, go.mod, GOPATH 风格的 "/src/", Warehouse 计算.
+// 如果 go.mod 位于 Roots 元素之下, 优先使用 go.mod.
+// 识别成功时 import paths 可能为空, 比如 GOROOT/src 本身.
+func ImportPathOf(abs string) (string, bool) {
 	if abs == "" {
-		return ""
+		return "", false
 	}
 	if abs[len(abs)-1] == os.PathSeparator {
 		abs = abs[:len(abs)-1]
 	}
 
+	// 最长匹配
+	root := ""
+	for _, dir := range Roots {
+		if len(dir) > len(root) && (abs == dir ||
+			strings.HasPrefix(abs, dir) && abs[len(dir)] == os.PathSeparator) {
+			root = dir
+		}
+	}
+	// 位于 root 之下的 go.mod 优先
+	m := LookModule(abs)
+	if root != "" && (m == nil || len(m.Dir) <= len(root)) {
+		if root == abs {
+			return "", true
+		}
+		return filepath.ToSlash(abs[len(root)+1:]), true
+	}
+
+	if importPaths, ok := m.ImportPath(abs); ok {
+		return importPaths, true
+	}
+
 	if strings.HasSuffix(abs, SrcElem[:4]) {
-		return ""
+		return "", true
 	}
 
 	pos := strings.Index(abs, SrcElem)
 	if pos != -1 {
-		return filepath.ToSlash(abs[pos+5:])
+		return filepath.ToSlash(abs[pos+5:]), true
 	}
 	for _, wh := range Warehouse {
 		pos = strings.Index(abs, wh.Host)
-		if pos == -1 {
+		if pos <= 0 {
 			continue
 		}
-		if abs[pos-1] == os.PathSeparator && abs[pos+len(wh.Host)] == os.PathSeparator {
-			return filepath.ToSlash(abs[pos:])
+		if abs[pos-1] == os.PathSeparator && (len(abs) == pos+len(wh.Host) ||
+			abs[pos+len(wh.Host)] == os.PathSeparator) {
+			return filepath.ToSlash(abs[pos:]), true
 		}
 	}
 
-	return ""
+	return "", false
 }
 
 // OSArchTest 提取并返回 go 文件名 name 中可识别的 goos, goarch, test 部分.
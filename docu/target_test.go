@@ -61,7 +61,9 @@ func TestOSArchTest(t *testing.T) {
 	}
 }
 
-func TestBuildForLinux(t *testing.T) {
+func TestBuildFor(t *testing.T) {
+	du := &Docu{GOOS: "linux", GOARCH: "amd64", CgoEnabled: true,
+		ReleaseTags: []string{"go1.1", "go1.18"}, Tags: []string{"foo"}}
 	tests := []struct {
 		want bool
 		name string
@@ -73,14 +75,33 @@ func TestBuildForLinux(t *testing.T) {
 		{false, "// +build ingore\npackage n"},
 		{false, "//+build ingore\npackage n"},
 		{false, "// +build linux3 windows\npackage n"},
-		{false, "// +buildlinux window\npackage n"},
+		{true, "// +buildlinux window\npackage n"},
 		{false, "// +build !linux window\npackage n"},
 		{true, "// +build linux window\npackage n"},
 		{true, "// +build window linux\npackage n"},
+		{true, "// +build amd64\npackage n"},
+		{false, "// +build windows\npackage n"},
+		{true, "// +build !windows\npackage n"},
+		{true, "// +build linux,amd64\npackage n"},
+		{false, "// +build linux,386\npackage n"},
+		{false, "// +build linux\n// +build 386\npackage n"},
+		{true, "// +build linux\n// +build cgo\npackage n"},
+		{true, "// +build unix,go1.18,foo\npackage n"},
+		{false, "// +build go1.19\npackage n"},
+		{false, "//go:build ignore\n\npackage n"},
+		{true, "//go:build (linux || darwin) && !386\n\npackage n"},
+		{true, "//go:build linux\n// +build windows\n\npackage n"},
+		{false, "//go:build linux &&\n\npackage n"},
 	}
 	for _, tt := range tests {
-		if got := buildForLinux([]byte(tt.name)); got != tt.want {
-			t.Errorf("buildForLinux(%q) = %v, want %v", tt.name, got, tt.want)
+		if got := buildFor([]byte(tt.name), du.MatchTag); got != tt.want {
+			t.Errorf("buildFor(%q) = %v, want %v", tt.name, got, tt.want)
 		}
 	}
+
+	du = &Docu{GOOS: "android", GOARCH: "arm64"}
+	if !buildFor([]byte("//go:build linux && !cgo\n\npackage n"), du.MatchTag) ||
+		!du.MatchOSArch("a_linux_arm64.go") || du.MatchOSArch("a_windows.go") {
+		t.Error("android should match linux but not windows")
+	}
 }
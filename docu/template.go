@@ -2,10 +2,16 @@ package docu
 
 import (
 	"bytes"
+	"errors"
 	"go/ast"
 	"go/doc"
+	"io/ioutil"
+	"os"
 	"path"
+	"path/filepath"
+	"strings"
 	"text/template"
+	"text/template/parse"
 )
 
 // Data 为模板提供执行数据.
@@ -15,26 +21,32 @@ type Data struct {
 	Key        string // 模板将要要处理的
 	Ext        string // 输出文件扩展名
 	// 方便起见包含了声明类型常量
-	IMPORT, CONST, VAR, TYPE, FUNC, METHOD, OTHER int
+	IMPORT, CONST, VAR, TYPE, FUNC, METHOD, EXAMPLE, OTHER int
 
 	buf    bytes.Buffer // 仅供模板内部处理文本用
 	filter func(*ast.File) bool
+	order  func(*ast.File)
+	file   *ast.File   // File 的缓存, 模板的各个 block 共用
+	key    string      // file 对应的 Key
+	pkg    *PackageDoc // Package 的缓存, 随 file 更新
 }
 
 // NewData 返回需要自建立 Data.Docu 的 Data 实例.
 func NewData() *Data {
 	return &Data{
-		IMPORT: ImportNum,
-		CONST:  ConstNum,
-		VAR:    VarNum,
-		TYPE:   TypeNum,
-		FUNC:   FuncNum,
-		METHOD: MethodNum,
+		IMPORT:  ImportNum,
+		CONST:   ConstNum,
+		VAR:     VarNum,
+		TYPE:    TypeNum,
+		FUNC:    FuncNum,
+		METHOD:  MethodNum,
+		EXAMPLE: ExampleNum,
 	}
 }
 
 func (d *Data) Parse(path string, source interface{}) (importPaths string, err error) {
 	d.Ext = ""
+	d.file = nil
 	importPaths, err = d.Docu.Parse(path, source)
 	if err == nil {
 		d.ImportPath = importPaths
@@ -45,19 +57,67 @@ func (d *Data) Parse(path string, source interface{}) (importPaths string, err e
 }
 
 func (d *Data) SetFilter(filter func(*ast.File) bool) {
-	d.filter = filter
+	d.filter, d.file = filter, nil
 }
 
-// File 返回 MergePackageFiles d.Key 的值
+// SetOrder 设置 File 返回值的顶级声明排序函数, 参见 Orders.
+func (d *Data) SetOrder(order func(*ast.File)) {
+	d.order, d.file = order, nil
+}
+
+// File 返回 MergePackageFiles d.Key 的值, 同一 Key 只合并一次.
+// 模板函数 clear 剔除过声明时重新合并, 同早期每次调用都返回新的合并结果.
+// OmitDeprecated 为 true 时剔除已废弃的声明, 参见 DeprecatedFileFilter.
 func (d *Data) File() *ast.File {
+	if d.file != nil && d.key == d.Key && !hasClearedDecl(d.file.Decls) {
+		return d.file
+	}
 	f := d.Docu.MergePackageFiles(d.Key)
+	if f != nil {
+		// 单文件包返回解析的文件, clear 只应修改 Decls 的副本
+		cp := *f
+		cp.Decls = append([]ast.Decl(nil), f.Decls...)
+		f = &cp
+	}
 	ClearComments(f)
 	if d.filter != nil {
 		d.filter(f)
 	}
+	if OmitDeprecated {
+		DeprecatedFileFilter(f)
+	}
+	if d.order != nil {
+		d.order(f)
+	}
+	d.file, d.key, d.pkg = f, d.Key, nil
 	return f
 }
 
+// hasClearedDecl 返回 decls 中是否有被模板函数 clear 剔除的声明.
+func hasClearedDecl(decls []ast.Decl) bool {
+	for _, decl := range decls {
+		if decl == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Normal 返回 File 按 godoc 习惯分组的声明, 参见 GroupNormal.
+func (d *Data) Normal() *Normal {
+	return GroupNormal(d.File().Decls)
+}
+
+// Package 返回 File 的 go/doc 风格文档模型, 参见 NewPackageDoc.
+// 不同于 decls, methods 等函数, 模型不改变 File 的声明.
+func (d *Data) Package() *PackageDoc {
+	f := d.File()
+	if d.pkg == nil {
+		d.pkg = NewPackageDoc(d.Key, f)
+	}
+	return d.pkg
+}
+
 // Type 设置 d.Ext
 func (d *Data) Type(ext string) string {
 	d.Ext = ext
@@ -65,8 +125,12 @@ func (d *Data) Type(ext string) string {
 }
 
 // Code 返回 decl 的代码, 支持 Const,Var,Type,Func
+// 示例函数返回 ExampleCode.
 func (d *Data) Code(decl ast.Decl) string {
 	num := NodeNumber(decl)
+	if num == ExampleNum {
+		return ExampleCode(decl.(*ast.FuncDecl))
+	}
 	if num == FuncNum || num == MethodNum {
 		return FuncLit(decl.(*ast.FuncDecl))
 	}
@@ -87,17 +151,18 @@ func (d *Data) Code(decl ast.Decl) string {
 }
 
 // Text 返回 decl 的注释, 支持 Const,Var,Type,Func
+// 多语言文档只保留 Langs 选中的译文, 参见 SelectLangs.
 func (d *Data) Text(decl ast.Decl) string {
 	num := NodeNumber(decl)
-	if num == FuncNum || num == MethodNum {
+	if num == FuncNum || num == MethodNum || num == ExampleNum {
 		fdecl := decl.(*ast.FuncDecl)
-		return fdecl.Doc.Text()
+		return SelectLangs(fdecl.Doc.Text(), Langs)
 	}
 	if num != ConstNum && num != VarNum && num != TypeNum {
 		return ""
 	}
 	genDecl := decl.(*ast.GenDecl)
-	return genDecl.Doc.Text()
+	return SelectLangs(genDecl.Doc.Text(), Langs)
 }
 
 // Fold 利用 doc.ToText 对文档 text 进行折叠.
@@ -107,16 +172,102 @@ func (d *Data) Fold(text string) string {
 	return d.buf.String()
 }
 
+// LiteralDoc 返回文档 text, 其中缩进的预格式化文本前后分别加上 start, end 行,
+// 为空时不加. 用于需要标记文字块的格式.
+func LiteralDoc(text, start, end string) string {
+	var lines []string
+	pre, blanks := false, 0
+	for _, line := range strings.Split(trimText(text), "\n") {
+		if strings.TrimSpace(line) == "" {
+			blanks++
+			continue
+		}
+		indented := line[0] == ' ' || line[0] == '\t'
+		if !pre && blanks == 0 && len(lines) != 0 {
+			// 预格式化文本位于空行之后, 否则为段落的延续
+			indented = false
+		}
+		if pre && !indented && end != "" {
+			lines = append(lines, end)
+		}
+		for ; blanks != 0; blanks-- {
+			lines = append(lines, "")
+		}
+		if !pre && indented && start != "" {
+			lines = append(lines, start)
+		}
+		pre = indented
+		lines = append(lines, line)
+	}
+	if pre && end != "" {
+		lines = append(lines, end)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
 // FuncsMap 是默认的 template.FuncMap
 var FuncsMap = template.FuncMap{
 	"base":                 path.Base,
-	"progress":             TranslationProgress,
+	"progress":             ParagraphProgress,
+	"groupProgress":        TranslationProgress,
 	"canonicalImportPaths": CanonicalImportPaths,
-	"license":              License,
-	"nodeNum":              NodeNumber,
-	"lineWrap":             LineWrapper,
-	"identLit":             DeclIdentLit,
-	"originDoc":            OriginDoc,
+	"license": func(file *ast.File) string {
+		// 返回 file 的 License 文本
+		lic, _ := License(file)
+		return lic
+	},
+	"nodeNum":      NodeNumber,
+	"lineWrap":     LineWrapper,
+	"identLit":     DeclIdentLit,
+	"originDoc":    OriginDoc,
+	"normal":       GroupNormal,
+	"anchor":       Anchor,
+	"anchors":      Anchors,
+	"specNames":    SpecNames,
+	"recvIdentLit": RecvIdentLit,
+	"htmlDoc":      HTMLDoc,
+	"selectLangs": func(text string) string {
+		// 多语言文档只保留 Langs 选中的译文
+		return SelectLangs(text, Langs)
+	},
+	"translations": func(text string) []Translation {
+		// 返回多语言文档中 Langs 选中的译文
+		_, trans := SplitTranslations(text)
+		return SelectTranslations(trans, Langs)
+	},
+	"htmlCode":      HTMLCode,
+	"exampleCode":   ExampleCode,
+	"exampleSuffix": ExampleSuffix,
+	"isDeprecated":  IsDeprecatedDecl,
+	"noteTitle": func(marker string) string {
+		// 同 godoc, 返回注释标记 marker 的标题, 比如 "Bug"
+		return marker[:1] + strings.ToLower(marker[1:])
+	},
+	"literalDoc": LiteralDoc,
+	"toText": func(indent, text string) string {
+		// 同 go doc, 以 80 列折叠文档 text
+		var buf bytes.Buffer
+		doc.ToText(&buf, text, indent, indent+"\t", 80)
+		return buf.String()
+	},
+	"trim": strings.TrimSpace,
+	"indent": func(prefix, text string) string {
+		// 为 text 的非空行加上前缀 prefix
+		lines := strings.Split(text, "\n")
+		for i, line := range lines {
+			if line != "" {
+				lines[i] = prefix + line
+			}
+		}
+		return strings.Join(lines, "\n")
+	},
+	"underline": func(c, title string) string {
+		// 返回 title 的下划线, 以字节计算长度, 不短于显示宽度
+		return strings.Repeat(c, len(title))
+	},
 	"imports": func(file *ast.File) string {
 		// 返回 file 的 import 代码
 		return ImportsString(file.Imports)
@@ -163,7 +314,7 @@ var FuncsMap = template.FuncMap{
 		return decls[first:last]
 	},
 	"indexConstructor": func(decls []ast.Decl, typeLit string) int {
-		// 未来实现了常规排序后, 不推荐使用此方法
+		// 不推荐使用此方法, 请使用 normal
 		for i, n := range decls {
 			num := NodeNumber(n)
 			if num == FuncNum {
@@ -178,7 +329,7 @@ var FuncsMap = template.FuncMap{
 		return -1
 	},
 	"methods": func(decls []ast.Decl, typeLit string) []ast.Decl {
-		// 未来实现了常规排序后, 不推荐使用此方法
+		// 不推荐使用此方法, 请使用 normal
 		first := -1
 		last := len(decls)
 		for i, n := range decls {
@@ -211,14 +362,14 @@ var FuncsMap = template.FuncMap{
 		return decls[first:last]
 	},
 	"clear": func(decls []ast.Decl, pos int) string {
-		// 未来实现了常规排序后, 不推荐使用此方法
+		// 不推荐使用此方法, 请使用 normal
 		if pos >= 0 && pos < len(decls) {
 			decls[pos] = nil
 		}
 		return ""
 	},
 	"trimRight": func(decls []ast.Decl) []ast.Decl {
-		// 未来实现了常规排序后, 不推荐使用此方法
+		// 不推荐使用此方法, 请使用 normal
 		for i, n := range decls {
 			if n != nil {
 				return decls[i:]
@@ -229,3 +380,82 @@ var FuncsMap = template.FuncMap{
 }
 
 const DefaultTemplate = MarkdownTemplate
+
+// Templates 为按名称选用的内置模板.
+var Templates = map[string]string{
+	"markdown": MarkdownTemplate,
+	"html":     HTMLTemplate,
+	"rst":      RSTTemplate,
+	"asciidoc": AsciiDocTemplate,
+	"text":     TextTemplate,
+}
+
+// ParseTemplates 依次解析 names 并返回要执行的模板, 映射了 FuncsMap.
+// names 的元素可以是 Templates 中的内置模板名称, 模板文件, 目录或 glob 模式,
+// 目录表示其中全部的 ".tmpl" 文件. 后解析的同名 define, block 覆盖之前的定义,
+// 因此模板文件可以只 define 内置模板的部分 block.
+// 返回最后一个顶层内容非空的模板文件, 否则返回内置模板.
+// names 中没有内置模板名称时以 DefaultTemplate 为基础.
+func ParseTemplates(names ...string) (*template.Template, error) {
+	tpl := template.New("Godocu").Funcs(FuncsMap)
+	builtin := false
+	for _, name := range names {
+		if _, ok := Templates[name]; ok {
+			builtin = true
+			break
+		}
+	}
+	if !builtin {
+		names = append([]string{""}, names...)
+	}
+
+	main := tpl
+	for _, name := range names {
+		if text, ok := Templates[name]; ok || name == "" {
+			if !ok {
+				text = DefaultTemplate
+			}
+			if _, err := tpl.Parse(text); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		files, err := templateFiles(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			b, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			t, err := tpl.New(filepath.Base(file)).Parse(string(b))
+			if err != nil {
+				return nil, err
+			}
+			if t.Tree != nil && !parse.IsEmptyTree(t.Tree.Root) {
+				main = t
+			}
+		}
+	}
+	return main, nil
+}
+
+// templateFiles 返回 name 表示的模板文件, name 可以是文件, 目录或 glob 模式.
+func templateFiles(name string) ([]string, error) {
+	fi, err := os.Stat(name)
+	if err == nil && !fi.IsDir() {
+		return []string{name}, nil
+	}
+	pattern := name
+	if err == nil {
+		pattern = filepath.Join(name, "*.tmpl")
+	} else if !strings.ContainsAny(name, "*?[") {
+		return nil, err
+	}
+	files, err := filepath.Glob(pattern)
+	if err == nil && len(files) == 0 {
+		err = errors.New("no template files matched: " + pattern)
+	}
+	return files, err
+}
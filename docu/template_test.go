@@ -0,0 +1,135 @@
+package docu
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func executeTemplates(t *testing.T, names ...string) (string, string) {
+	tpl, err := ParseTemplates(names...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewData()
+	d.Docu = New()
+	d.SetFilter(ExportedFileFilter)
+	key, err := d.Parse(filepath.Join(GOROOT, "src", "container", "list"), nil)
+	if err != nil || key == "" {
+		t.Skip("container/list not found in GOROOT")
+	}
+	d.Key = key
+	var buf bytes.Buffer
+	if err = tpl.Execute(&buf, d); err != nil {
+		t.Fatal(err)
+	}
+	return d.Ext, buf.String()
+}
+
+func TestTemplates(t *testing.T) {
+	exts := map[string]string{
+		"markdown": "md", "html": "html", "rst": "rst", "asciidoc": "adoc", "text": "txt",
+	}
+	for name := range Templates {
+		ext, out := executeTemplates(t, name)
+		if ext != exts[name] || !strings.Contains(out, "PushBackList") ||
+			!strings.Contains(out, "Package list implements a doubly linked list.") {
+			t.Errorf("%s: ext %q\n%s", name, ext, out)
+		}
+	}
+}
+
+func TestParseTemplates(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "godocu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	err = ioutil.WriteFile(filepath.Join(tmp, "header.tmpl"),
+		[]byte(`{{define "header"}}HEADER {{.ImportPath}}{{end}}`), 0644)
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(tmp, "footer.tmpl"),
+			[]byte(`{{define "footer"}}FOOTER{{end}}`), 0644)
+	}
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(tmp, "whole.txt"),
+			[]byte(`{{$.Type "txt"}}WHOLE {{.ImportPath}}`), 0644)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 目录中的 block 覆盖缺省模板
+	ext, out := executeTemplates(t, tmp)
+	if ext != "md" || !strings.HasPrefix(out, "HEADER container/list") ||
+		!strings.HasSuffix(out, "FOOTER") || !strings.Contains(out, "### List.PushBack") {
+		t.Errorf("ParseTemplates dir:\n%s", out)
+	}
+
+	// glob 覆盖指定的内置模板
+	ext, out = executeTemplates(t, "html", filepath.Join(tmp, "f*.tmpl"))
+	if ext != "html" || !strings.HasPrefix(out, "<!DOCTYPE html>") ||
+		!strings.HasSuffix(out, "FOOTER") {
+		t.Errorf("ParseTemplates glob:\n%s", out)
+	}
+
+	// 完整的模板文件
+	ext, out = executeTemplates(t, filepath.Join(tmp, "whole.txt"))
+	if ext != "txt" || out != "WHOLE container/list" {
+		t.Errorf("ParseTemplates file: %q %q", ext, out)
+	}
+
+	if _, err = ParseTemplates(filepath.Join(tmp, "*.none")); err == nil {
+		t.Error("ParseTemplates want error for no matched files")
+	}
+}
+
+func TestLiteralDoc(t *testing.T) {
+	text := "Para\n\tnot code.\n\nExample:\n\n\tcode\n\n\tmore\nEnd.\n"
+	want := "Para\n\tnot code.\n\nExample:\n\n::\n\tcode\n\n\tmore\nEnd.\n"
+	if got := LiteralDoc(text, "::", ""); got != want {
+		t.Errorf("LiteralDoc =\n%q\nwant\n%q", got, want)
+	}
+	want = "Para\n\tnot code.\n\nExample:\n\n....\n\tcode\n\n\tmore\n....\nEnd.\n"
+	if got := LiteralDoc(text, "....", "...."); got != want {
+		t.Errorf("LiteralDoc =\n%q\nwant\n%q", got, want)
+	}
+}
+
+// 早期模板以 clear 剔除构造函数, 之后在同一 Data 上执行的模板仍应得到完整的 File.
+func TestLegacyTemplateClear(t *testing.T) {
+	legacy, err := ParseTemplates(filepath.Join("testdata", "legacy_markdown.tmpl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl, err := ParseTemplates("markdown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewData()
+	d.Docu = New()
+	d.SetFilter(ExportedFileFilter)
+	key, err := d.Parse(filepath.Join(GOROOT, "src", "container", "list"), nil)
+	if err != nil || key == "" {
+		t.Skip("container/list not found in GOROOT")
+	}
+	d.Key = key
+	var buf bytes.Buffer
+	if err = legacy.Execute(&buf, d); err != nil {
+		t.Fatal(err)
+	}
+	if hasClearedDecl(d.File().Decls) {
+		t.Fatal("File returns cleared decls")
+	}
+	buf.Reset()
+	if err = tpl.Execute(&buf, d); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "func New() *List") {
+		t.Errorf("markdown after legacy template:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,54 @@
+package docu
+
+// TextTemplate 为内置的纯文本模板, 格式类似 go doc -all 的输出.
+const TextTemplate = `{{define "decl"}}{{trim .}}
+{{end}}{{define "example"}}Example{{if $s := exampleSuffix .}} ({{$s}}){{end}}:
+{{with toText "    " (selectLangs .Doc.Text)}}{{.}}
+{{end}}{{indent "    " (exampleCode .)}}
+
+{{end}}{{/*
+此模板输出类似 go doc -all 的纯文本, 声明的文档缩进 4 个空格.
+各部分以 block 定义, 执行数据都是 Data, 可用 define 单独覆盖:
+header, consts, vars, funcs, types, notes, footer.
+示例函数以 example 输出, 方法 ExamplesOf 返回属于某个声明的示例.
+*/}}{{if eq .Key .ImportPath}}{{$.Type "txt"}}{{block "header" .}}{{$this := .File}}{{/*
+*/}}package {{$this.Name.Name}} // import "{{.ImportPath}}"
+
+{{if $trans := progress $this}}Translation Progress: {{$trans}}%
+
+{{end}}{{if $this.Doc}}{{toText "" (selectLangs $this.Doc.Text)}}
+{{end}}{{range .Normal.ExamplesOf ""}}{{template "example" .}}{{end}}{{end}}{{/*
+
+常量, 变量
+*/}}{{block "consts" .}}{{range $i, $x := .Normal.Consts}}{{if eq $i 0}}CONSTANTS
+
+{{end}}{{template "decl" $.Code $x}}{{toText "    " ($.Text $x)}}
+{{end}}{{end}}{{/*
+*/}}{{block "vars" .}}{{range $i, $x := .Normal.Vars}}{{if eq $i 0}}VARIABLES
+
+{{end}}{{template "decl" $.Code $x}}{{toText "    " ($.Text $x)}}
+{{end}}{{end}}{{/*
+
+函数
+*/}}{{block "funcs" .}}{{$g := .Normal}}{{range $i, $x := $g.Funcs}}{{if eq $i 0}}FUNCTIONS
+
+{{end}}{{template "decl" $.Code $x}}{{toText "    " ($.Text $x)}}
+{{range $g.ExamplesOf (anchor $x)}}{{template "example" .}}{{end}}{{end}}{{end}}{{/*
+
+类型
+*/}}{{block "types" .}}{{$g := .Normal}}{{range $i, $t := $g.Types}}{{if eq $i 0}}TYPES
+
+{{end}}{{template "decl" $.Code $t.Decl}}{{toText "    " ($.Text $t.Decl)}}
+{{range $g.ExamplesOf $t.Name}}{{template "example" .}}{{end}}{{range $x := $t.Consts}}{{template "decl" $.Code $x}}{{toText "    " ($.Text $x)}}
+{{end}}{{range $x := $t.Vars}}{{template "decl" $.Code $x}}{{toText "    " ($.Text $x)}}
+{{end}}{{range $x := $t.Funcs}}{{template "decl" $.Code $x}}{{toText "    " ($.Text $x)}}
+{{range $g.ExamplesOf (anchor $x)}}{{template "example" .}}{{end}}{{end}}{{/*
+*/}}{{range $m := $t.Methods}}{{template "decl" $.Code $m}}{{toText "    " ($.Text $m)}}
+{{range $g.ExamplesOf (anchor $m)}}{{template "example" .}}{{end}}{{end}}{{end}}{{end}}{{/*
+*/}}{{block "notes" .}}{{range $marker, $notes := .Package.Notes}}{{$marker}}S
+
+{{range $notes}}{{toText "    " .Body.Text}}
+{{end}}{{end}}{{end}}{{/*
+*/}}{{block "footer" .}}{{if $x := license .File}}LICENSE
+
+{{toText "" $x}}{{end}}{{end}}{{end}}`
@@ -0,0 +1,352 @@
+package docu
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"go/ast"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// 翻译单元交换格式
+const (
+	FormatPO    = "po"    // gettext PO
+	FormatXLIFF = "xliff" // XLIFF 1.2
+)
+
+// FormatExt 为交换格式对应的文件扩展名.
+var FormatExt = map[string]string{
+	FormatPO:    ".po",
+	FormatXLIFF: ".xlf",
+}
+
+// Unit 表示一个翻译单元, 供 CAT 工具使用.
+type Unit struct {
+	// ID 为 import paths 和标识符, 形如 "container/list List.Init".
+	// 同一标识符多次出现时, 之后的加 "#n" 后缀, n 从 2 开始.
+	ID     string
+	Origin string // 原文
+	Trans  string // 译文, 未翻译时为空
+	Fuzzy  bool   // 译文具有过期标记, 参见 GoDocu_Stale_line
+}
+
+// eachTrans 依次以标识符, 原文, 译文和文档调用 fn, 未翻译的译文为空.
+// 原文以 SplitComments 或 OriginDoc 对应, 否则文档本身为原文.
+// Godocu 风格文档会调用 ClearComments.
+func eachTrans(file *ast.File, fn func(ident, origin, trans string, doc *ast.CommentGroup)) {
+	godocu := IsGodocuFile(file)
+	if godocu {
+		ClearComments(file)
+	}
+	eachDoc(file, func(ident string, doc *ast.CommentGroup) {
+		origin, trans := SplitComments(doc.Text())
+		if origin == "" && godocu {
+			if o := OriginDoc(file.Comments, doc); o != nil {
+				origin = o.Text()
+			}
+		}
+		origin, trans = trimText(origin), trimText(trans)
+		if origin == "" {
+			origin, trans = trans, ""
+		} else if normalize(origin) == normalize(trans) {
+			trans = ""
+		}
+		fn(ident, origin, trans, doc)
+	})
+}
+
+// eachUnitDoc 同 eachTrans, 以翻译单元 ID 代替标识符.
+func eachUnitDoc(key string, file *ast.File,
+	fn func(id, origin, trans string, doc *ast.CommentGroup)) {
+
+	seen := make(map[string]int)
+	eachTrans(file, func(ident, origin, trans string, doc *ast.CommentGroup) {
+		id := key + " " + ident
+		if seen[ident]++; seen[ident] > 1 {
+			id += "#" + strconv.Itoa(seen[ident])
+		}
+		fn(id, origin, trans, doc)
+	})
+}
+
+// Units 返回 import paths 为 key 的翻译文档 file 中的全部翻译单元.
+// 过期标记不出现在译文中, 而是设置 Fuzzy. Units 调用 ClearComments 清理 file 的尾注释.
+func Units(key string, file *ast.File) (units []Unit) {
+	eachUnitDoc(key, file, func(id, origin, trans string, _ *ast.CommentGroup) {
+		fuzzy := strings.HasPrefix(trans, GoDocu_Stale_line+"\n")
+		if fuzzy {
+			trans = strings.TrimLeft(trans[len(GoDocu_Stale_line):], "\n")
+		}
+		units = append(units, Unit{id, origin, trans, fuzzy})
+	})
+	return
+}
+
+// ImportUnits 以 units 中的译文更新翻译文档 file, 返回更新的文档个数.
+// 忽略没有译文或原文与 file 中不同的单元. Fuzzy 单元的译文添加过期标记.
+// 合并文档替换分割线之后的译文, Godocu 风格文档替换原文之后的译文,
+// 未翻译的文档合并为双语文档, 保持 file 原有的结构.
+// 多语言文档只替换 SplitComments 选用的译文, 参见 AddTranslation.
+func ImportUnits(key string, file *ast.File, units []Unit) (n int) {
+	m := make(map[string]Unit, len(units))
+	for _, u := range units {
+		m[u.ID] = u
+	}
+	eachUnitDoc(key, file, func(id, origin, trans string, doc *ast.CommentGroup) {
+		u, ok := m[id]
+		if !ok || u.Trans == "" || normalize(u.Origin) != normalize(origin) ||
+			normalize(u.Origin) == normalize(u.Trans) {
+			return
+		}
+		text := u.Trans
+		if u.Fuzzy {
+			text = GoDocu_Stale_line + "\n\n" + text
+		}
+		if normalize(text) == normalize(trans) {
+			return
+		}
+		if s := doc.Text(); HasLangs(s) {
+			// 多语言文档只替换选用的译文, 保留原文和其他译文
+			lang, ok := selectedLang(s)
+			if !ok {
+				return
+			}
+			AddTranslation(doc, lang, text)
+			n++
+			return
+		}
+		list := commentGroup(text).List
+		slash := doc.List[0].Slash
+		i, _ := translationStart(doc)
+		switch {
+		case i != 0:
+			doc.List = append(doc.List[:i], list...)
+		case trans != "":
+			// Godocu 风格, 保持 doc.Pos() 以便 OriginDoc 对应原文
+			for _, c := range list {
+				c.Slash = slash
+			}
+			doc.List = list
+		default:
+			MergeDoc(&ast.CommentGroup{List: list}, doc)
+		}
+		n++
+	})
+	return
+}
+
+// WriteUnits 以交换格式 format 输出 import paths 为 key 的翻译单元 units 到 w.
+// lang 为译文的语言, 形如 zh_CN.
+func WriteUnits(w io.Writer, format, key, lang string, units []Unit) error {
+	switch format {
+	case FormatPO:
+		return writePO(w, key, lang, units)
+	case FormatXLIFF:
+		return writeXLIFF(w, key, lang, units)
+	}
+	return errors.New("unknown format: " + format)
+}
+
+// ReadUnits 从 r 读取交换格式 format 的翻译单元.
+func ReadUnits(r io.Reader, format string) ([]Unit, error) {
+	switch format {
+	case FormatPO:
+		return readPO(r)
+	case FormatXLIFF:
+		return readXLIFF(r)
+	}
+	return nil, errors.New("unknown format: " + format)
+}
+
+// PO 格式以 msgctxt 保存 ID, 以 msgid, msgstr 保存原文和译文.
+
+var poEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+var poUnescaper = strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n", `\t`, "\t")
+
+func writePO(w io.Writer, key, lang string, units []Unit) (err error) {
+	_, err = fmt.Fprintf(w, "# Godocu translations for %s\nmsgid \"\"\nmsgstr \"\"\n"+
+		"\"Content-Type: text/plain; charset=UTF-8\\n\"\n\"Language: %s\\n\"\n",
+		key, lang)
+	for _, u := range units {
+		if err != nil {
+			break
+		}
+		_, err = fmt.Fprintln(w)
+		if err == nil && u.Fuzzy {
+			_, err = fmt.Fprintln(w, "#, fuzzy")
+		}
+		if err == nil {
+			err = poString(w, "msgctxt", u.ID)
+		}
+		if err == nil {
+			err = poString(w, "msgid", u.Origin)
+		}
+		if err == nil {
+			err = poString(w, "msgstr", u.Trans)
+		}
+	}
+	return
+}
+
+// poString 输出 PO 格式的字符串 s, 多行时每行一个字符串.
+func poString(w io.Writer, keyword, s string) (err error) {
+	if strings.IndexByte(strings.TrimSuffix(s, "\n"), '\n') == -1 {
+		_, err = fmt.Fprintf(w, "%s \"%s\"\n", keyword, poEscaper.Replace(s))
+		return
+	}
+	_, err = fmt.Fprintf(w, "%s \"\"\n", keyword)
+	for err == nil && s != "" {
+		i := strings.IndexByte(s, '\n') + 1
+		if i == 0 {
+			i = len(s)
+		}
+		_, err = fmt.Fprintf(w, "\"%s\"\n", poEscaper.Replace(s[:i]))
+		s = s[i:]
+	}
+	return
+}
+
+func readPO(r io.Reader) (units []Unit, err error) {
+	var u Unit
+	var field *string
+	var ctxt, id bool
+	flush := func() {
+		if ctxt && id {
+			units = append(units, u)
+		}
+		u, field, ctxt, id = Unit{}, nil, false, false
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			flush()
+			continue
+		}
+		if line[0] == '#' {
+			if strings.HasPrefix(line, "#,") && strings.Contains(line, "fuzzy") {
+				u.Fuzzy = true
+			}
+			continue
+		}
+		keyword := ""
+		if line[0] != '"' {
+			i := strings.IndexByte(line, ' ')
+			if i == -1 {
+				return nil, fmt.Errorf("po:%d: invalid line", n)
+			}
+			keyword, line = line[:i], strings.TrimSpace(line[i:])
+		}
+		if len(line) < 2 || line[0] != '"' || line[len(line)-1] != '"' {
+			return nil, fmt.Errorf("po:%d: invalid string", n)
+		}
+		s := poUnescaper.Replace(line[1 : len(line)-1])
+		switch keyword {
+		case "":
+			if field == nil {
+				return nil, fmt.Errorf("po:%d: unexpected string", n)
+			}
+			*field += s
+			continue
+		case "msgctxt":
+			if ctxt || id {
+				flush()
+			}
+			field, ctxt = &u.ID, true
+		case "msgid":
+			field, id = &u.Origin, true
+		case "msgstr":
+			field = &u.Trans
+		default:
+			// 不支持复数等形式
+			field = new(string)
+		}
+		*field = s
+	}
+	flush()
+	return units, scanner.Err()
+}
+
+// XLIFF 格式以 trans-unit 的 id 保存 ID, 以 state 区分过期的译文.
+
+type xliffDoc struct {
+	XMLName xml.Name  `xml:"xliff"`
+	Version string    `xml:"version,attr"`
+	Xmlns   string    `xml:"xmlns,attr"`
+	File    xliffFile `xml:"file"`
+}
+
+type xliffFile struct {
+	Original string      `xml:"original,attr"`
+	Source   string      `xml:"source-language,attr"`
+	Target   string      `xml:"target-language,attr"`
+	Datatype string      `xml:"datatype,attr"`
+	Units    []xliffUnit `xml:"body>trans-unit"`
+}
+
+type xliffUnit struct {
+	ID     string      `xml:"id,attr"`
+	Source string      `xml:"source"`
+	Target xliffTarget `xml:"target"`
+}
+
+type xliffTarget struct {
+	State string `xml:"state,attr,omitempty"`
+	Text  string `xml:",chardata"`
+}
+
+const (
+	xliffTranslated  = "translated"
+	xliffNeedsTrans  = "needs-translation"
+	xliffNeedsReview = "needs-review-translation"
+)
+
+func writeXLIFF(w io.Writer, key, lang string, units []Unit) error {
+	doc := xliffDoc{
+		Version: "1.2",
+		Xmlns:   "urn:oasis:names:tc:xliff:document:1.2",
+		File: xliffFile{
+			Original: key,
+			Source:   "en",
+			Target:   strings.Replace(lang, "_", "-", -1),
+			Datatype: "plaintext",
+		},
+	}
+	for _, u := range units {
+		x := xliffUnit{ID: u.ID, Source: u.Origin,
+			Target: xliffTarget{xliffTranslated, u.Trans}}
+		if u.Fuzzy {
+			x.Target.State = xliffNeedsReview
+		} else if u.Trans == "" {
+			x.Target.State = xliffNeedsTrans
+		}
+		doc.File.Units = append(doc.File.Units, x)
+	}
+	_, err := io.WriteString(w, xml.Header)
+	if err == nil {
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "  ")
+		err = enc.Encode(doc)
+	}
+	if err == nil {
+		_, err = io.WriteString(w, "\n")
+	}
+	return err
+}
+
+func readXLIFF(r io.Reader) (units []Unit, err error) {
+	var doc xliffDoc
+	if err = xml.NewDecoder(r).Decode(&doc); err != nil {
+		return
+	}
+	for _, x := range doc.File.Units {
+		units = append(units, Unit{x.ID, x.Source, x.Target.Text,
+			x.Target.State == xliffNeedsReview})
+	}
+	return
+}
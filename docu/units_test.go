@@ -0,0 +1,152 @@
+package docu
+
+import (
+	"bytes"
+	"errors"
+	"go/ast"
+	"strings"
+	"testing"
+)
+
+const testUnitsSource = `package p
+
+// A does a.
+
+// A 做 a.
+func A()
+
+// B does b.
+// It is "good".
+
+// ___GoDocu_Stale___
+//
+// B 做 b.
+func B()
+
+// C does c.
+func C()
+
+// T is t.
+
+// T 是 t.
+type T struct {
+	// F is f.
+	F int
+}
+`
+
+func TestUnits(t *testing.T) {
+	units := Units("p", parseGodocu(t, testUnitsSource))
+	want := []Unit{
+		{"p T", "T is t.\n", "T 是 t.\n", false},
+		{"p T.F", "F is f.\n", "", false},
+		{"p A", "A does a.\n", "A 做 a.\n", false},
+		{"p B", "B does b.\nIt is \"good\".\n", "B 做 b.\n", true},
+		{"p C", "C does c.\n", "", false},
+	}
+	if len(units) != len(want) {
+		t.Fatalf("Units = %+v", units)
+	}
+	for i := range want {
+		if units[i] != want[i] {
+			t.Errorf("Units[%d] = %+v, want %+v", i, units[i], want[i])
+		}
+	}
+
+	for _, format := range []string{FormatPO, FormatXLIFF} {
+		var buf bytes.Buffer
+		if err := WriteUnits(&buf, format, "p", "zh_CN", units); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadUnits(&buf, format)
+		if err != nil {
+			t.Fatal(format, err)
+		}
+		if len(got) != len(units) {
+			t.Fatalf("%s: ReadUnits = %+v", format, got)
+		}
+		for i := range units {
+			if got[i] != units[i] {
+				t.Errorf("%s: ReadUnits[%d] = %+v, want %+v", format, i, got[i], units[i])
+			}
+		}
+	}
+
+	units[0].Origin = "T is changed.\n"
+	units[3].Trans, units[3].Fuzzy = "B 做 b.\n它很好.\n", false
+	units[4].Trans = "C 做 c.\n"
+	file := parseGodocu(t, testUnitsSource)
+	if n := ImportUnits("p", file, units); n != 2 {
+		t.Errorf("ImportUnits = %d, want 2", n)
+	}
+	var buf bytes.Buffer
+	if err := Fprint(&buf, file); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, s := range []string{
+		"// B does b.\n// It is \"good\".\n\n// B 做 b.\n// 它很好.\nfunc B()",
+		"// C does c.\n\n// C 做 c.\nfunc C()",
+		"// T is t.\n\n// T 是 t.\ntype T",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("missing %q in:\n%s", s, out)
+		}
+	}
+	if strings.Contains(out, GoDocu_Stale_line) {
+		t.Errorf("stale line not removed:\n%s", out)
+	}
+}
+
+func TestImportUnitsLangs(t *testing.T) {
+	const src = `package p
+
+// A does a.
+//
+// ___GoDocu_Dividing_line___ zh_CN
+//
+// A 做 a.
+//
+// ___GoDocu_Dividing_line___ zh_TW
+//
+// A 作 a.
+func A()
+`
+	Langs = []string{"zh_TW"}
+	defer func() { Langs = nil }()
+	file := parseGodocu(t, src)
+	units := Units("p", file)
+	if len(units) != 1 || units[0].Trans != "A 作 a.\n" {
+		t.Fatalf("Units = %+v", units)
+	}
+	units[0].Trans = "A 執行 a.\n"
+	if n := ImportUnits("p", file, units); n != 1 {
+		t.Fatalf("ImportUnits = %d, want 1", n)
+	}
+	origin, trans := SplitTranslations(file.Decls[0].(*ast.FuncDecl).Doc.Text())
+	if origin != "A does a.\n" || len(trans) != 2 ||
+		trans[0] != (Translation{"zh_CN", "A 做 a.\n"}) ||
+		trans[1] != (Translation{"zh_TW", "A 執行 a.\n"}) {
+		t.Errorf("ImportUnits = %q, %q", origin, trans)
+	}
+}
+
+// failWriter 在写入 n 次之后返回错误.
+type failWriter struct{ n int }
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	if w.n == 0 {
+		return 0, errors.New("write failed")
+	}
+	w.n--
+	return len(p), nil
+}
+
+func TestWriteUnitsError(t *testing.T) {
+	units := []Unit{{"p A", "A does a.\n", "A 做 a.\n", true}}
+	for n := 0; n < 6; n++ {
+		if err := WriteUnits(&failWriter{n}, FormatPO, "p", "zh_CN", units); err == nil {
+			t.Errorf("WriteUnits with %d successful writes = nil", n)
+		}
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"text/template"
 
@@ -38,6 +39,13 @@ The commands are:
   list    generate godocu style documents list
   merge   merge source doc to target
   replace replace the target untranslated section in source translated section
+  serve   serve the source and target documents over HTTP
+  fill    fill the target untranslated docs from the source translation memory
+  stale   list the target translations whose origin has changed in the source
+  export  export the source translations as PO or XLIFF files to target
+  import  import the target PO or XLIFF files into the source translations
+  glossary check the source translations against the glossary terms
+  lint    check the target godocu style documents of the source packages
 
 The source are:
 
@@ -50,16 +58,54 @@ The target are:
 
 The arguments are:
 
+  -breaking
+      diff and first only output breaking API changes, the target is the old version
+  -cgo
+      the "cgo" build tag is satisfied (default true)
+  -docdiff string
+      doc changes output for diff, first and stale, "block"|"line"|"word" (default "block")
+  -examples
+      include Example functions from _test.go files for code, tmpl, merge and serve
   -file string
-      template file for tmpl
+      templates for tmpl, built-in template name "markdown"|"html"|"rst"|"asciidoc"|"text",
+      template file, directory or glob, joined by the os path list separator,
+      glossary file for glossary (default "glossary_<lang>.txt" in the source or parent directory)
+  -format string
+      file format for export and import, "po"|"xliff" (default "po")
+  -goarch string
+      target architecture for file name suffix and build constraints (default "amd64")
+  -gofmt
+      align declarations for code, merge, replace and fill exactly as gofmt does
+  -goos string
+      target operating system for file name suffix and build constraints (default "linux")
   -gopath string
       specifies GOPATH (default $GOPATH)
   -goroot string
       specifies GOROOT (default $GOROOT)
+  -http string
+      HTTP service address for serve (default "localhost:6060")
+  -j int
+      the number of packages processed in parallel (default the number of CPUs)
+  -json
+      diff, first, stale, glossary and lint output one JSON record per line for each difference,
+      code output the JSON documentation model per package
   -lang string
-      the lang pattern for the output file, form like en or zh_CN
+      the lang pattern for the output file, form like en or zh_CN,
+      merge with "mul" generates multilingual documents from the -langs documents
+  -langs string
+      a comma-separated list of langs selected from multilingual documents,
+      the order is the priority, form like zh_CN,zh_TW
+  -nodeprecated
+      omit deprecated symbols for code and tmpl
+  -notes string
+      a comma-separated list of note markers like BUG(who) for code, tmpl, merge and serve,
+      form like BUG,TODO,NOTE (default "BUG")
+  -order string
+      declarations order for code and tmpl, "index"|"normal"|"source" (default "index")
   -p string
       package filtering, "package"|"main"|"test" (default "package")
+  -tags string
+      a comma-separated list of additional build tags to consider satisfied
   -u
       show unexported symbols as well as exported
 `
@@ -72,12 +118,49 @@ func flagUsage(err string) {
 	os.Exit(2)
 }
 
+// format 为 export, import 指令的文件格式, 参见 docu.FormatExt.
+var format string
+
+// order 为 code, tmpl 指令顶级声明的排序方式, 参见 docu.Orders.
+var order string
+
+// jsonOut 表示以 JSON 格式输出结构化结果.
+var jsonOut bool
+
+// langs 为多语言文档选用的 lang 列表, 参见 docu.Langs.
+var langs string
+
+// notes 为注释标记列表, 参见 docu.NoteMarkers.
+var notes string
+
+// breaking 表示 diff, first 指令只输出不兼容的 API 变更.
+var breaking bool
+
+// docDiff 为 diff, first 指令文档差异的输出方式, 参见 docu.DiffPrinter.
+var docDiff string
+
 func flagParse() (command, source, target, lib, lang, file string, u bool) {
-	var gopath string
+	var gopath, tags string
+	flag.BoolVar(&breaking, "breaking", false, "")
+	flag.StringVar(&docDiff, "docdiff", docu.DocBlock, "")
+	flag.BoolVar(&docu.Examples, "examples", false, "")
 	flag.StringVar(&file, "file", "", "")
+	flag.BoolVar(&docu.Gofmt, "gofmt", false, "")
+	flag.StringVar(&format, "format", docu.FormatPO, "")
+	flag.StringVar(&order, "order", "index", "")
+	flag.BoolVar(&docu.CgoEnabled, "cgo", docu.CgoEnabled, "")
+	flag.StringVar(&tags, "tags", "", "")
+	flag.StringVar(&docu.GOOS, "goos", docu.GOOS, "")
+	flag.StringVar(&docu.GOARCH, "goarch", docu.GOARCH, "")
 	flag.StringVar(&docu.GOROOT, "goroot", docu.GOROOT, "")
 	flag.StringVar(&gopath, "gopath", os.Getenv("GOPATH"), "")
+	flag.StringVar(&httpAddr, "http", "localhost:6060", "")
+	flag.IntVar(&jobs, "j", runtime.NumCPU(), "")
+	flag.BoolVar(&jsonOut, "json", false, "")
 	flag.StringVar(&lang, "lang", "", "")
+	flag.StringVar(&langs, "langs", "", "")
+	flag.BoolVar(&docu.OmitDeprecated, "nodeprecated", false, "")
+	flag.StringVar(&notes, "notes", strings.Join(docu.NoteMarkers, ","), "")
 	flag.StringVar(&lib, "p", "package", "")
 	flag.BoolVar(&u, "u", false, "")
 
@@ -109,6 +192,35 @@ func flagParse() (command, source, target, lib, lang, file string, u bool) {
 	if pos := strings.Index(libs, lib); pos == -1 || libs[pos+len(lib)] != ' ' {
 		flagUsage("-p must be one of package,test,main. but got" + lib)
 	}
+	docu.Tags = strings.FieldsFunc(tags, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	if !docu.IsKnownOS(docu.GOOS) {
+		flagUsage("-goos is unknown operating system: " + docu.GOOS)
+	}
+	if !docu.IsKnownArch(docu.GOARCH) {
+		flagUsage("-goarch is unknown architecture: " + docu.GOARCH)
+	}
+	if docDiff != docu.DocBlock && docDiff != docu.DocLine && docDiff != docu.DocWord {
+		flagUsage("-docdiff must be one of block,line,word. but got " + docDiff)
+	}
+	if docu.FormatExt[format] == "" {
+		flagUsage("-format must be one of po,xliff. but got " + format)
+	}
+	if docu.Orders[order] == nil {
+		flagUsage("-order must be one of index,normal,source. but got " + order)
+	}
+	for _, l := range strings.FieldsFunc(langs, func(r rune) bool {
+		return r == ',' || r == ' '
+	}) {
+		if l = docu.LangNormal(l); l == "" {
+			flagUsage("-langs is invalid lang list: " + langs)
+		}
+		docu.Langs = append(docu.Langs, l)
+	}
+	docu.NoteMarkers = strings.FieldsFunc(notes, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
 
 	args = flag.Args()
 
@@ -144,38 +256,25 @@ func flagParse() (command, source, target, lib, lang, file string, u bool) {
 // 多文档输出分割线
 var sp = "\n\n" + strings.Repeat("/", 80) + "\n\n"
 
-func skipOSArch(f func(string) bool) func(string) bool {
-	return func(name string) bool {
-		if !f(name) {
-			return false
-		}
-		if docu.IsNormalName(name) {
-			return true
-		}
-		goos, goarch, _ := docu.OSArchTest(name)
-		return (goos == "" || goos == "linux") && (goarch == "" || goarch == "amd64")
-	}
-}
-
 func genNameFilter(lib, lang string) func(string) bool {
 	if lang == "" {
 		switch lib {
 		case "package":
-			return skipOSArch(docu.PackageFilter)
+			return docu.PackageFilter
 		case "test":
-			return skipOSArch(docu.TestFilter)
+			return docu.TestFilter
 		case "main":
-			return skipOSArch(docu.MainFilter)
+			return docu.MainFilter
 		}
 		panic("BUG")
 	}
 	switch lib {
 	case "package":
-		return skipOSArch(docu.GenNameFilter("doc_" + lang + ".go"))
+		return docu.GenNameFilter("doc_" + lang + ".go")
 	case "test":
-		return skipOSArch(docu.GenNameFilter("test_" + lang + ".go"))
+		return docu.GenNameFilter("test_" + lang + ".go")
 	case "main":
-		return skipOSArch(docu.GenNameFilter("main_" + lang + ".go"))
+		return docu.GenNameFilter("main_" + lang + ".go")
 	}
 	panic("BUG")
 }
@@ -207,13 +306,13 @@ func createFile(path, name string) (file *os.File, err error) {
 }
 
 func main() {
-	const cmds = "code tmpl list diff first tree merge replace "
+	const cmds = "code tmpl list export glossary diff first tree merge replace serve fill stale import lint "
 	var err error
 	var info os.FileInfo
 	command, source, target, lib, lang, file, u := flagParse()
 
 	pos := strings.Index(cmds, command)
-	if pos == -1 || cmds[pos+len(command)] != ' ' || target == "" && pos > 14 {
+	if pos == -1 || cmds[pos+len(command)] != ' ' || target == "" && pos > 30 {
 
 		fmt.Fprintln(os.Stderr, usage)
 		log.Fatal("invalid command or target")
@@ -236,15 +335,20 @@ func main() {
 		flagUsage("source must be existing directory")
 	}
 
-	// 计算导入路径的偏移量
-	offset := posForImport(source)
-	if offset == -1 {
-		flagUsage("invalid source: " + source)
-	} else if target == "--" {
-		// 同目录输出
-		target = source[:offset]
-	} else {
+	// target 为 "--" 表示同目录输出
+	if target != "" && target != "--" {
 		target = docu.Abs(target)
+		if target, err = filepath.Abs(target); err != nil {
+			flagUsage("invalid target: " + err.Error())
+		}
+		// 以 target 为基础目录计算 import paths
+		docu.Roots = append(docu.Roots, target)
+	}
+
+	// 计算导入路径
+	imp, ok := docu.ImportPathOf(dirOf(source))
+	if !ok {
+		flagUsage("invalid source: " + source)
 	}
 	if command == "tree" {
 		sub = true
@@ -258,14 +362,15 @@ func main() {
 
 	switch command {
 	case "code":
-		err = codeMode(ch, offset, target, lib, lang, u)
+		err = codeMode(ch, target, lib, lang, u)
 	case "tree":
 		// 对比目录结构
 		var d1 bool
 		prefix := fmt.Sprintf("source: %s\ntarget: %s\n\nsource target path\n",
 			source, target)
 
-		d1, err = treeMode(prefix, "  path  none ", "  path  file ", ch, source, target)
+		target = targetDir(target, source, imp)
+		d1, err = treeMode(prefix, "  path  none ", "  path  file ", ch, imp, source, target)
 		if err != nil {
 			break
 		}
@@ -275,35 +380,66 @@ func main() {
 			prefix = ""
 		}
 
-		if offset < len(source) {
-			target = filepath.Join(target, source[offset:])
-			source = source[:offset]
-		}
-
 		ch = make(chan interface{})
 		go walkPath(ch, sub, target)
-		_, err = treeMode(prefix, "  none  path ", "  file  path ", ch, target, source)
+		_, err = treeMode(prefix, "  none  path ", "  file  path ", ch, imp, target, source)
 	case "first", "diff":
-		err = diffMode(command, ch, offset, target, lib, lang, u)
+		err = diffMode(command, ch, target, lib, lang, u)
 	case "merge":
-		err = mergeMode(ch, offset, target, lib, lang)
+		err = mergeMode(ch, target, lib, lang)
 	case "replace":
-		err = replaceMode(ch, offset, target, lib, lang)
+		err = replaceMode(ch, target, lib, lang)
 	case "list":
-		err = listMode(ch, offset, target, lib, lang)
-	case "tmpl":
-		tpl := template.New("Godocu").Funcs(docu.FuncsMap)
-		if file != "" {
-			tpl, err = tpl.ParseFiles(file)
-		} else {
-			tpl, err = tpl.Parse(docu.DefaultTemplate)
+		err = listMode(ch, target, lib, lang)
+	case "stale":
+		err = staleMode(ch, target, lib, lang)
+	case "fill":
+		err = fillMode(ch, sub, source, target, imp, lib, lang)
+	case "export":
+		err = exportMode(ch, target, lib, lang)
+	case "import":
+		err = importMode(ch, target, lib, lang)
+	case "lint":
+		err = lintMode(ch, target, lib, lang)
+	case "glossary":
+		var g docu.Glossary
+		if file == "" && lang == "" {
+			err = errors.New("missing argument lang or file")
+		} else if file == "" {
+			file = lookGlossary(dirOf(source), lang)
+		}
+		if err == nil {
+			g, err = docu.ReadGlossary(file)
+		}
+		if err != nil {
+			<-ch
+			break
 		}
+		err = glossaryMode(g, ch, lib, lang)
+	case "serve":
+		// 无需遍历, 停止 walkPath
+		<-ch
+		ch <- io.EOF
+		<-ch
+		root := dirOf(source)
+		// module 中的包目录由 module path 计算, 否则为 root 下的 import paths
+		mod := docu.LookModule(root)
+		if p, ok := mod.ImportPath(root); !ok || p != imp {
+			mod = nil
+			if imp != "" {
+				root = strings.TrimSuffix(root, string(filepath.Separator)+filepath.FromSlash(imp))
+			}
+		}
+		err = serveMode(root, mod, target, lib, lang, u)
+	case "tmpl":
+		var tpl *template.Template
+		tpl, err = docu.ParseTemplates(filepath.SplitList(file)...)
 
 		if err != nil {
 			<-ch
 			break
 		}
-		err = tmplMode(tpl, ch, offset, target, lib, lang, u)
+		err = tmplMode(tpl, ch, target, lib, lang, u)
 	}
 
 	close(ch)
@@ -314,101 +450,59 @@ func main() {
 
 // 模板
 func tmplMode(tmpl *template.Template, ch chan interface{},
-	offset int, target, lib, lang string, u bool) (err error) {
+	target, lib, lang string, u bool) error {
 
-	var buf bytes.Buffer
-
-	var ok bool
-	var key, source, dst string
-	var paths string
-	var output *os.File
-
-	out := false
-	du := docu.NewData()
-	du.Docu = docu.New()
-	du.Docu.Filter = genNameFilter(lib, "")
+	var out bool
+	du := docu.New()
+	du.Filter = genNameFilter(lib, "")
 
-	tu := docu.New()
-	if target != "" {
-		tu.Filter = genNameFilter(lib, lang)
-	}
+	return parallel(ch, func(source string) (func() error, error) {
+		d := docu.NewData()
+		d.Docu = du
+		d.SetOrder(docu.Orders[order])
 
-	for i := <-ch; i != nil; i = <-ch {
-		if err, ok = i.(error); !ok {
-			source = i.(string)
-			paths, err = du.Parse(source, nil)
-		}
-		if err != nil {
-			break
-		}
-		if len(paths) == 0 {
-			ch <- nil
-			continue
+		paths, err := d.Parse(source, nil)
+		if err != nil || len(paths) == 0 {
+			return nil, err
 		}
 
-		key = paths
+		key := paths
+		var dst string
+		if target != "" {
+			dst = targetDir(target, source, key)
+		}
 
 		if !u {
+			d.SetFilter(docu.ExportedFileFilter)
 			if target != "" {
-				// 计算目标路径, 第一个可能是单文件
-				if dst == "" && strings.HasSuffix(source, ".go") {
-					source = filepath.Dir(source)
-				}
-				dst = filepath.Join(target, source[offset:])
-
 				// 以目标过滤源
+				tu := docu.New()
+				tu.Filter = genNameFilter(lib, lang)
 				paths, err = tu.Parse(dst, nil)
 				if os.IsNotExist(err) {
 					err = nil
 				}
 				if err != nil {
-					break
+					return nil, err
 				}
 				dis := tu.MergePackageFiles(key)
 				if dis != nil && paths == key {
-					du.SetFilter(docu.SortDecl(dis.Decls).Filter)
-				} else {
-					du.SetFilter(docu.ExportedFileFilter)
+					d.SetFilter(docu.SortDecl(dis.Decls).Filter)
 				}
-			} else {
-				du.SetFilter(docu.ExportedFileFilter)
 			}
 		}
 
-		buf.Truncate(0)
-
-		du.Key = key
-		if err = tmpl.Execute(&buf, du); err != nil {
-			break
-		}
-
-		if du.Ext == "" {
-			continue
-		}
-
-		output, err = createFile(dst, genFileName(lib, lang, du.Ext))
-		if err != nil {
-			break
-		}
-
-		if out && target == "" {
-			_, err = os.Stdout.WriteString(sp)
-		}
-		out = true
-		if err == nil {
-			_, err = output.Write(buf.Bytes())
-		}
-		if output != os.Stdout {
-			output.Close()
+		var buf bytes.Buffer
+		d.Key = key
+		if err = tmpl.Execute(&buf, d); err != nil || d.Ext == "" {
+			return nil, err
 		}
 
-		if err != nil {
-			break
-		}
-
-		ch <- nil
-	}
-	return
+		name := genFileName(lib, lang, d.Ext)
+		return func() error {
+			return writeOutput(dst, name, &out, buf.Bytes())
+		}, nil
+	})
 }
 
 // walkPath 通道类型约定:
@@ -438,241 +532,397 @@ func walkPath(ch chan interface{}, sub bool, source string) {
 	ch <- nil
 }
 
-func codeMode(ch chan interface{},
-	offset int, target, lib, lang string, u bool) (err error) {
+// jobs 为并发处理包的 goroutine 数量.
+var jobs int
 
-	var ok bool
-	var key, source, dst string
-	var paths string
+// task 为 parallel 中单个路径的处理结果.
+type task struct {
+	done chan struct{}
+	emit func() error
+	err  error
+}
 
-	output := os.Stdout
+// parallel 从 walkPath 的通道 ch 接收路径, 以 jobs 个 goroutine 并发执行 work,
+// 并按遍历次序依次执行 work 返回的 emit, 以保证输出次序确定.
+// work 负责解析, 过滤, 合并, 生成输出等耗时操作, 返回的 emit 为 nil 表示无需输出.
+// emit 负责写入输出和修改共享状态, 它们总在同一个 goroutine 中依次执行.
+// 发生错误时停止遍历, 返回按遍历次序的第一个错误.
+func parallel(ch chan interface{}, work func(source string) (func() error, error)) (err error) {
+	n := jobs
+	if n < 1 {
+		n = 1
+	}
+	queue := make(chan *task, n)
+	sem := make(chan struct{}, n)
+	stop := make(chan struct{})
+
+	go func() {
+		defer close(queue)
+		for i := <-ch; i != nil; i = <-ch {
+			t := &task{done: make(chan struct{})}
+			if e, ok := i.(error); ok {
+				t.err = e
+				close(t.done)
+			} else {
+				sem <- struct{}{}
+				go func(source string) {
+					t.emit, t.err = work(source)
+					<-sem
+					close(t.done)
+				}(i.(string))
+			}
 
-	out := false
-	du := docu.New()
-	du.Filter = genNameFilter(lib, "")
+			select {
+			case queue <- t:
+				ch <- nil
+			case <-stop:
+				ch <- io.EOF
+				<-ch
+				return
+			}
+		}
+	}()
 
-	tu := docu.New()
-	if target != "" {
-		tu.Filter = genNameFilter(lib, lang)
+	for t := range queue {
+		<-t.done
+		if err != nil {
+			continue
+		}
+		err = t.err
+		if err == nil && t.emit != nil {
+			err = t.emit()
+		}
+		if err != nil {
+			close(stop)
+		}
 	}
+	return
+}
 
-	fname := genFileName(lib, lang, ".go")
-
-	for i := <-ch; i != nil; i = <-ch {
-		if err, ok = i.(error); !ok {
-			source = i.(string)
-			paths, err = du.Parse(source, nil)
+// writeOutput 把 b 写入 path 目录下的 name 文件, 两者之一为空时写入 os.Stdout.
+// 多次写入 os.Stdout 时以 sp 分割, out 记录是否已写入过 os.Stdout.
+func writeOutput(path, name string, out *bool, b []byte) error {
+	output, err := createFile(path, name)
+	if err != nil {
+		return err
+	}
+	if output == os.Stdout {
+		if *out {
+			_, err = output.WriteString(sp)
 		}
+		*out = true
+	}
+	if err == nil {
+		_, err = output.Write(b)
+	}
+	if output != os.Stdout {
+		if e := output.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
 
-		if docu.IsMultiplePkgError(err) {
-			err = nil
-			ch <- nil
+// lookLang 返回 lang, 为空时返回 dir 中唯一的 lib 类别 Godocu 文档的 lang.
+// 用于未指定 lang 时逐包推断, 只是为了过滤.
+func lookLang(lang, dir, lib string) string {
+	if lang != "" {
+		return lang
+	}
+	prefix := strings.TrimSuffix(genFileName(lib, "x", ""), "x")
+	fis, _ := ioutil.ReadDir(dir)
+	for _, fi := range fis {
+		name := fi.Name()
+		if fi.IsDir() || !strings.HasSuffix(name, ".go") {
 			continue
 		}
+		if lang != "" || !strings.HasPrefix(name, prefix) {
+			return ""
+		}
+		lang = docu.LangOf(name)
+	}
+	return lang
+}
 
-		if err != nil {
-			break
+func codeMode(ch chan interface{},
+	target, lib, lang string, u bool) error {
+
+	var out bool
+	du := docu.New()
+	du.Filter = genNameFilter(lib, "")
+
+	return parallel(ch, func(source string) (func() error, error) {
+		paths, err := du.Parse(source, nil)
+		if docu.IsMultiplePkgError(err) {
+			return nil, nil
 		}
-		if len(paths) == 0 {
-			ch <- nil
-			continue
+		if err != nil || len(paths) == 0 {
+			return nil, err
 		}
 
-		key = paths
+		key := paths
 		file := du.MergePackageFiles(key)
+		if docu.OmitDeprecated {
+			docu.DeprecatedFileFilter(file)
+		}
+		unresolved := file.Unresolved
 		file.Unresolved = nil
+
+		var dst, fname string
 		if target != "" {
-			// 计算目标路径, 第一个可能是单文件
-			if dst == "" && strings.HasSuffix(source, ".go") {
-				source = filepath.Dir(source)
-			}
-			dst = filepath.Join(target, source[offset:])
+			dst = targetDir(target, source, key)
+			fname = genFileName(lib, lang, ".go")
 		}
 
 		if !u {
+			filtered := false
 			if target != "" {
 				// 以目标过滤源
+				tu := docu.New()
+				tu.Filter = genNameFilter(lib, lookLang(lang, dst, lib))
 				paths, err = tu.Parse(dst, nil)
 				if os.IsNotExist(err) {
 					err = nil
 				}
 				if err != nil {
-					break
+					return nil, err
 				}
 				dis := tu.MergePackageFiles(key)
-				if dis != nil && paths == key {
+				if filtered = dis != nil && paths == key; filtered {
 					docu.SortDecl(dis.Decls).Filter(file)
-				} else {
-					docu.ExportedFileFilter(file)
-				}
-
-				// 自动提取第一个 lang, 只是为了过滤
-				if dis != nil && lang == "" {
-					lang = tu.NormalLang(key)
-					tu.Filter = genNameFilter(lib, tu.NormalLang(key))
-					lang = "."
 				}
-
-			} else {
+			}
+			if !filtered {
 				docu.ExportedFileFilter(file)
 			}
 		}
-		if target != "" && lang != "" && lang != "." {
-			output, err = createFile(dst, fname)
-		}
-		if err != nil {
-			break
-		}
 
-		if out && target == "" {
-			_, err = os.Stdout.WriteString(sp)
-		}
-		out = true
-		if err == nil {
-			err = docu.Fprint(output, file)
-		}
-
-		if output != os.Stdout {
-			output.Close()
+		var buf bytes.Buffer
+		docu.Orders[order](file)
+		if jsonOut {
+			// 输出数据模型, 保留 Godocu 风格以便提取原文
+			var b []byte
+			file.Unresolved = unresolved
+			b, err = json.MarshalIndent(docu.NewModel(du.FileSet, key, file), "", "  ")
+			buf.Write(b)
+			buf.WriteByte('\n')
+			if fname != "" {
+				fname = genFileName(lib, lang, ".json")
+			}
+		} else {
+			err = docu.Fprint(&buf, file)
 		}
-
 		if err != nil {
-			break
+			return nil, err
 		}
-
-		ch <- nil
-	}
-	return
+		return func() error {
+			return writeOutput(dst, fname, &out, buf.Bytes())
+		}, nil
+	})
 }
 
 func diffMode(command string, ch chan interface{},
-	offset int, target, lib, lang string, u bool) (err error) {
-
-	var ok, diff bool
-	var key, source string
-	var paths string
-	var output *os.File
+	target, lib, lang string, u bool) error {
 
-	fileDiff := docu.FirstDiff
-	if command == "diff" {
-		fileDiff = docu.Diff
-	}
-	du, tu := docu.New(), docu.New()
+	first := command == "first"
+	printer := docu.DiffPrinter{Doc: docDiff, Context: 3}
+	du := docu.New()
 	du.Filter = genNameFilter(lib, "")
-	tu.Filter = genNameFilter(lib, lang)
 
-	for i := <-ch; i != nil; i = <-ch {
-		if err, ok = i.(error); !ok {
-			source = i.(string)
-			paths, err = du.Parse(source, nil)
-		}
+	return parallel(ch, func(source string) (func() error, error) {
+		paths, err := du.Parse(source, nil)
 		if docu.IsMultiplePkgError(err) {
-			err = nil
-			ch <- nil
-			continue
+			return nil, nil
 		}
-		if err != nil {
-			break
-		}
-		if len(paths) == 0 {
-			ch <- nil
-			continue
+		if err != nil || len(paths) == 0 {
+			return nil, err
 		}
 
 		// 只对比相同的包. 不能有错.
-		key = paths
-		if strings.HasSuffix(source, ".go") {
-			source = filepath.Dir(source)
-		}
-		paths, err = tu.Parse(filepath.Join(target, source[offset:]), nil)
+		key := paths
+		dst := targetDir(target, source, key)
+		lang := lookLang(lang, dst, lib)
+		tu := docu.New()
+		tu.Filter = genNameFilter(lib, lang)
+		paths, err = tu.Parse(dst, nil)
 		if os.IsNotExist(err) {
 			err = nil
 		}
-		if err != nil {
-			break
+		if err != nil || len(paths) == 0 {
+			return nil, err
 		}
-		if len(paths) == 0 {
-			ch <- nil
-			continue
+
+		var records []docu.DiffRecord
+		if key != paths {
+			records = []docu.DiffRecord{{
+				Kind:   "package",
+				Change: docu.DiffSignature,
+				Compat: docu.Breaking,
+				Source: "package " + key,
+				Target: "package " + paths,
+			}}
+		} else {
+			src, dis := du.MergePackageFiles(key), tu.MergePackageFiles(key)
+			if lang != "" {
+				docu.SortDecl(dis.Decls).Filter(src)
+			} else if !u {
+				docu.ExportedFileFilter(src)
+				docu.ExportedFileFilter(dis)
+			}
+			records = docu.Diffs(src, dis, first && !breaking)
 		}
 
-		diff, err = docu.TextDiff(output, "package "+key, "package "+paths)
+		// 统计全部差异, first 只有一个差异, 不输出统计
+		summary := docu.SummarizeDiffs(records)
+		summary.Package = key
+		if breaking {
+			records = docu.BreakingDiffs(records)
+			if first && len(records) > 1 {
+				records = records[:1]
+			}
+		}
 
+		var buf bytes.Buffer
+		if jsonOut {
+			// 每行一个差异记录, 最后一行为统计
+			enc := json.NewEncoder(&buf)
+			for _, r := range records {
+				r.Package = key
+				if err = enc.Encode(r); err != nil {
+					return nil, err
+				}
+			}
+			if !first && len(records) != 0 {
+				err = enc.Encode(summary)
+			}
+		} else if key != paths {
+			err = printer.Fprint(&buf, records)
+			buf.WriteString(sp)
+		} else if len(records) != 0 {
+			err = printer.Fprint(&buf, records)
+			if !first {
+				fmt.Fprintf(&buf, "SUMMARY: %d breaking, %d compatible, %d cosmetic\n",
+					summary.Breaking, summary.Compatible, summary.Cosmetic)
+			}
+			buf.WriteString("FROM: package " + key)
+		}
 		if err != nil {
-			break
+			return nil, err
 		}
 
-		if diff {
-			ch <- nil
-			io.WriteString(output, sp)
-			continue
+		return func() error {
+			_, err := os.Stdout.Write(buf.Bytes())
+			return err
+		}, nil
+	})
+}
+
+// staleMode 输出 target 翻译文档中原文已变更的过期翻译, 含保存的原文和 source 中的新原文.
+func staleMode(ch chan interface{}, target, lib, lang string) error {
+	du := docu.New()
+	du.Filter = genNameFilter(lib, "")
+
+	return parallel(ch, func(source string) (func() error, error) {
+		paths, err := du.Parse(source, nil)
+		if docu.IsMultiplePkgError(err) {
+			return nil, nil
+		}
+		if err != nil || len(paths) == 0 {
+			return nil, err
 		}
 
-		src, dis := du.MergePackageFiles(key), tu.MergePackageFiles(key)
-		// 自动提取第一个 lang, 只是为了过滤
-		if dis != nil && lang == "" {
-			lang = tu.NormalLang(key)
-			tu.Filter = genNameFilter(lib, lang)
-			if lang == "" {
-				lang = "."
-			}
+		key := paths
+		dst := targetDir(target, source, key)
+		lang := lookLang(lang, dst, lib)
+		if lang == "" {
+			return nil, nil
+		}
+		tu := docu.New()
+		tu.Filter = genNameFilter(lib, lang)
+		paths, err = tu.Parse(filepath.Join(dst, genFileName(lib, lang, ".go")), nil)
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		if err != nil || paths != key {
+			return nil, err
 		}
 
-		if lang != "." {
-			docu.SortDecl(dis.Decls).Filter(src)
-		} else if !u {
-			docu.ExportedFileFilter(src)
-			docu.ExportedFileFilter(dis)
+		records := docu.Stales(du.MergePackageFiles(key), tu.MergePackageFiles(key))
+		if len(records) == 0 {
+			return nil, nil
 		}
 
-		diff, err = fileDiff(output, src, dis)
-		if diff && err == nil {
-			_, err = io.WriteString(output, "FROM: package "+key)
+		var buf bytes.Buffer
+		if jsonOut {
+			enc := json.NewEncoder(&buf)
+			for _, r := range records {
+				r.Package = key
+				if err = enc.Encode(r); err != nil {
+					return nil, err
+				}
+			}
+		} else {
+			err = fprintStales(&buf, key, records)
 		}
+		if err != nil {
+			return nil, err
+		}
+		return func() error {
+			_, err := os.Stdout.Write(buf.Bytes())
+			return err
+		}, nil
+	})
+}
 
+// fprintStales 以 STALE: 加 import paths 和标识符开头输出 records,
+// 按 docDiff 输出新旧原文的差异, 缺省输出 OLD:/NEW: 文本块.
+func fprintStales(w io.Writer, key string, records []docu.StaleRecord) (err error) {
+	const prefix = "    "
+	for _, r := range records {
+		_, err = fmt.Fprintf(w, "STALE: %s %s\n", key, r.Ident)
+		switch {
+		case err != nil:
+		case docDiff == docu.DocLine:
+			err = docu.LineDiff(w, prefix, r.OldOrigin, r.NewOrigin, 3)
+		case docDiff == docu.DocWord:
+			err = docu.WordDiff(w, prefix, r.OldOrigin, r.NewOrigin, 3)
+		default:
+			_, err = fmt.Fprint(w, "OLD:\n", docu.LineWrapper(r.OldOrigin, prefix, 80),
+				"NEW:\n", docu.LineWrapper(r.NewOrigin, prefix, 80))
+		}
+		if err == nil {
+			_, err = fmt.Fprintln(w)
+		}
 		if err != nil {
 			break
 		}
-		ch <- nil
 	}
 	return
 }
 
-// posForImport 计算 import paths 开始的偏移量
-func posForImport(s string) (pos int) {
-	if strings.HasSuffix(s, ".go") {
-		s = filepath.Dir(s)
-	}
-	if strings.HasSuffix(s, docu.SrcElem[:4]) {
-		return len(s) + 1
+// dirOf 返回 source 所在的目录, source 可以是 Go 源文件.
+func dirOf(source string) string {
+	if strings.HasSuffix(source, ".go") {
+		return filepath.Dir(source)
 	}
+	return source
+}
 
-	pos = strings.Index(s, docu.SrcElem)
-	if pos != -1 {
-		pos += len(docu.SrcElem)
-		return
-	}
-	for _, wh := range docu.Warehouse {
-		pos = strings.Index(s, wh.Host)
-		if pos == -1 {
-			continue
-		}
-		if s[pos-1] == os.PathSeparator && s[pos+len(wh.Host)] == os.PathSeparator {
-			return pos
-		}
+// targetDir 返回 import paths 为 key 的 source 包在 target 下对应的目录.
+// target 为 "--" 时返回 source 所在目录.
+func targetDir(target, source, key string) string {
+	if target == "--" {
+		return dirOf(source)
 	}
-
-	return -1
+	return filepath.Join(target, filepath.FromSlash(key))
 }
 
-func treeMode(prefix, prenone, prefile string, ch chan interface{}, source, target string) (diff bool, err error) {
+// treeMode 对比 source, target 目录结构, 两者都是 import paths 为 imp 的目录.
+func treeMode(prefix, prenone, prefile string, ch chan interface{},
+	imp, source, target string) (diff bool, err error) {
+
 	var fi os.FileInfo
-	pos := posForImport(source)
-	if pos == -1 {
-		<-ch
-		err = errors.New("invalid path: " + source)
-		return
-	}
+	var rel string
 	output := os.Stdout
 	for i := <-ch; i != nil; i = <-ch {
 		err, _ = i.(error)
@@ -680,18 +930,17 @@ func treeMode(prefix, prenone, prefile string, ch chan interface{}, source, targ
 			break
 		}
 
-		source = i.(string)
-		source = source[pos:]
-
-		fi, err = os.Stat(filepath.Join(target, source))
+		rel = i.(string)[len(source):]
+		fi, err = os.Stat(target + rel)
+		rel = strings.TrimPrefix(imp+filepath.ToSlash(rel), "/")
 		if os.IsNotExist(err) {
-			_, err = fmt.Fprintln(output, prefix+prenone, source)
+			_, err = fmt.Fprintln(output, prefix+prenone, rel)
 			if err != nil {
 				break
 			}
 			diff, err, prefix = true, nil, ""
 		} else if err == nil && !fi.IsDir() { // 虽然不大能
-			_, err = fmt.Fprintln(output, prefix+prefile, source)
+			_, err = fmt.Fprintln(output, prefix+prefile, rel)
 			if err != nil {
 				break
 			}
@@ -706,238 +955,497 @@ func treeMode(prefix, prenone, prefile string, ch chan interface{}, source, targ
 }
 
 func mergeMode(ch chan interface{},
-	offset int, target, lib, lang string) (err error) {
+	target, lib, lang string) error {
 
-	var ok bool
-	var key, source, dst string
-	var paths string
-	var output *os.File
-
-	out := false
+	var out bool
+	// 未指定 lang 时输出到 os.Stdout
+	stdout := lang == ""
 	du := docu.New()
 	du.Filter = genNameFilter(lib, "")
 
-	tu := docu.New()
-	tu.Filter = genNameFilter(lib, lang)
-
-	fname := genFileName(lib, lang, ".go")
+	// langs 只用于选择合并到多语言文档的翻译, 输出保留全部译文
+	langs := docu.Langs
+	docu.Langs = nil
 
 	// 以 target 限制为过滤条件, 因此允许所有
-	for i := <-ch; i != nil; i = <-ch {
-		if err, ok = i.(error); !ok {
-			source = i.(string)
-			paths, err = du.Parse(source, nil)
-		}
-		// if docu.IsMultiplePkgError(err) {
-		//	err = nil
-		//	ch <- nil
-		//	continue
-		// }
-		if err != nil {
-			break
-		}
-		if len(paths) == 0 {
-			ch <- nil
-			continue
+	return parallel(ch, func(source string) (func() error, error) {
+		paths, err := du.Parse(source, nil)
+		if err != nil || len(paths) == 0 {
+			return nil, err
 		}
 
-		key = paths
-
-		// 计算目标路径, 第一个可能是单文件
-		if dst == "" && strings.HasSuffix(source, ".go") {
-			source = filepath.Dir(source)
+		key := paths
+		dst := targetDir(target, source, key)
+		lang := lookLang(lang, dst, lib)
+		fname := genFileName(lib, lang, ".go")
+
+		if lang == docu.LangMul {
+			src, err := mergeMul(du, key, dst, lib, langs)
+			if err != nil || src == nil {
+				return nil, err
+			}
+			var buf bytes.Buffer
+			if err = docu.Fprint(&buf, src); err != nil {
+				return nil, err
+			}
+			if stdout {
+				dst = ""
+			}
+			return func() error {
+				return writeOutput(dst, fname, &out, buf.Bytes())
+			}, nil
 		}
-		dst = filepath.Join(target, source[offset:])
 
+		tu := docu.New()
+		tu.Filter = genNameFilter(lib, lang)
 		paths, err = tu.Parse(filepath.Join(dst, fname), nil)
 		if os.IsNotExist(err) {
 			err = nil
 		}
-		if err != nil {
-			break
+		if err != nil || key != paths {
+			return nil, err
 		}
-		if key != paths {
-			ch <- nil
-			continue
-		}
-		dis := tu.MergePackageFiles(key)
-
-		// 自动提取第一个 lang, 只是为了过滤
 		if lang == "" {
-			lang = tu.NormalLang(key)
-			if lang == "" {
-				err = errors.New("missing argument lang")
-				break
-			}
-			tu.Filter = genNameFilter(lib, lang)
-			output = os.Stdout
-			fname = genFileName(lib, lang, ".go")
-			lang = "."
+			return nil, errors.New("missing argument lang")
 		}
 
+		dis := tu.MergePackageFiles(key)
 		src := du.MergePackageFiles(key)
 
 		// src 为输出结果, 用目标过滤源
 		docu.SortDecl(dis.Decls).Filter(src)
 
+		// 原文已变更的译文添加过期标记
+		docu.MarkStales(src, dis)
+
 		if !docu.EqualComment(src.Doc, dis.Doc) {
 			docu.MergeDoc(dis.Doc, src.Doc)
 		}
 
 		docu.MergeDeclsDoc(dis.Decls, src.Decls)
+		docu.MergeNotes(dis, src)
 
-		if lang != "." {
-			output, err = createFile(dst, fname)
+		var buf bytes.Buffer
+		src.Unresolved = nil // 防止万一 src 为 godocu
+		if err = docu.Fprint(&buf, src); err != nil {
+			return nil, err
 		}
-
-		if err != nil {
-			break
+		if stdout {
+			dst = ""
 		}
+		return func() error {
+			return writeOutput(dst, fname, &out, buf.Bytes())
+		}, nil
+	})
+}
 
-		if lang == "." {
-			if out {
-				_, err = os.Stdout.WriteString(sp)
+// mergeMul 返回以 langs 的翻译文档和已有的多语言文档为 key 的源码添加译文的结果.
+// 已有多语言文档时以其声明过滤源码, 否则只保留导出声明.
+func mergeMul(du *docu.Docu, key, dst, lib string, langs []string) (*ast.File, error) {
+	if len(langs) == 0 {
+		return nil, errors.New("missing argument langs")
+	}
+	parse := func(lang string) (*ast.File, error) {
+		tu := docu.New()
+		tu.Filter = genNameFilter(lib, lang)
+		paths, err := tu.Parse(filepath.Join(dst, genFileName(lib, lang, ".go")), nil)
+		if err != nil || paths != key {
+			if os.IsNotExist(err) {
+				err = nil
 			}
-			out = true
+			return nil, err
 		}
+		return tu.MergePackageFiles(key), nil
+	}
 
-		if err == nil {
-			src.Unresolved = nil // 防止万一 src 为 godocu
-			err = docu.Fprint(output, src)
-		}
-		if output != os.Stdout {
-			output.Close()
-		}
+	mul, err := parse(docu.LangMul)
+	if err != nil {
+		return nil, err
+	}
+	src := du.MergePackageFiles(key)
+	if mul != nil {
+		docu.SortDecl(mul.Decls).Filter(src)
+		docu.MergeLangs(src, mul, "")
+	} else if !docu.ExportedFileFilter(src) {
+		return nil, nil
+	}
 
+	// 翻译文档优先于已有的多语言文档
+	for _, lang := range langs {
+		file, err := parse(lang)
 		if err != nil {
-			break
+			return nil, err
+		}
+		if file != nil {
+			docu.MergeLangs(src, file, lang)
 		}
-		ch <- nil
 	}
-	return
+	src.Unresolved = nil
+	return src, nil
 }
 
-func replaceMode(ch chan interface{},
-	offset int, target, lib, lang string) (err error) {
-
-	var ok bool
-	var key, source, dst string
-	var paths string
-
-	output := os.Stdout
+// fillMode 以 source 下全部双语文档建立翻译记忆, 预填 target 下对应目录中未翻译的文档.
+// 预填记录输出到 Stdout, 未指定 lang 时预填结果输出到 Stdout, 记录输出到 Stderr.
+func fillMode(ch chan interface{}, sub bool,
+	source, target, imp, lib, lang string) error {
 
-	out := false
+	var out bool
+	stdout := lang == ""
+	report := os.Stdout
+	if stdout {
+		report = os.Stderr
+	}
 	du := docu.New()
 	du.Filter = genNameFilter(lib, lang)
 
-	tu := docu.New()
-	tu.Filter = du.Filter
+	memory := docu.NewMemory()
+	err := parallel(ch, func(source string) (func() error, error) {
+		pu := du
+		if stdout {
+			pu = docu.New()
+			pu.Filter = genNameFilter(lib, lookLang(lang, dirOf(source), lib))
+		}
+		paths, err := pu.Parse(source, nil)
+		if err != nil || len(paths) == 0 {
+			return nil, err
+		}
+		file := pu.MergePackageFiles(paths)
+		if !docu.IsGodocuFile(file) {
+			return nil, nil
+		}
+		return func() error {
+			memory.AddFile(file)
+			return nil
+		}, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var docs int
+	tch := make(chan interface{})
+	go walkPath(tch, sub, targetDir(target, source, imp))
+	err = parallel(tch, func(dir string) (func() error, error) {
+		lang := lookLang(lang, dir, lib)
+		if lang == "" {
+			return nil, nil
+		}
+		fname := genFileName(lib, lang, ".go")
+		tu := docu.New()
+		tu.Filter = genNameFilter(lib, lang)
+		key, err := tu.Parse(filepath.Join(dir, fname), nil)
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		if err != nil || key == "" {
+			return nil, err
+		}
 
-	fname := genFileName(lib, lang, ".go")
+		dis := tu.MergePackageFiles(key)
+		records := memory.Fill(dis)
+		if len(records) == 0 {
+			return nil, nil
+		}
 
-	// 以 target 限制为过滤条件, 因此允许所有
-	for i := <-ch; i != nil; i = <-ch {
-		if err, ok = i.(error); !ok {
-			source = i.(string)
-			paths, err = du.Parse(source, nil)
-		}
-		// if docu.IsMultiplePkgError(err) {
-		// 	err = nil
-		// 	ch <- nil
-		// 	continue
-		// }
-		if err != nil {
-			break
+		var buf bytes.Buffer
+		if err = docu.Fprint(&buf, dis); err != nil {
+			return nil, err
 		}
-		if len(paths) == 0 {
-			ch <- nil
-			continue
+		if stdout {
+			dir = ""
+		}
+		return func() error {
+			for _, r := range records {
+				fmt.Fprintf(report, "%s %s: %d exact, %d normalized of %d paragraphs\n",
+					key, r.Ident, r.Exact, r.Normal, r.Total)
+			}
+			docs += len(records)
+			return writeOutput(dir, fname, &out, buf.Bytes())
+		}, nil
+	})
+	close(tch)
+	if err == nil {
+		_, err = fmt.Fprintf(report, "filled %d docs, %d paragraphs in memory\n",
+			docs, memory.Len())
+	}
+	return err
+}
+
+// exportMode 输出 source 下翻译文档的全部翻译单元为 format 格式文件,
+// 文件名同翻译文档, 扩展名为 docu.FormatExt[format]. 未指定 target 时输出到 Stdout.
+func exportMode(ch chan interface{}, target, lib, lang string) error {
+	var out bool
+	ext := docu.FormatExt[format]
+	return parallel(ch, func(source string) (func() error, error) {
+		lang := lookLang(lang, dirOf(source), lib)
+		if lang == "" {
+			return nil, nil
+		}
+		du := docu.New()
+		du.Filter = genNameFilter(lib, lang)
+		paths, err := du.Parse(source, nil)
+		if err != nil || len(paths) == 0 {
+			return nil, err
 		}
 
-		key = paths
+		key := paths
+		units := docu.Units(key, du.MergePackageFiles(key))
+		if len(units) == 0 {
+			return nil, nil
+		}
+		var buf bytes.Buffer
+		if err = docu.WriteUnits(&buf, format, key, lang, units); err != nil {
+			return nil, err
+		}
+		var dst string
+		if target != "" {
+			dst = targetDir(target, source, key)
+		}
+		return func() error {
+			return writeOutput(dst, genFileName(lib, lang, ext), &out, buf.Bytes())
+		}, nil
+	})
+}
 
-		// 计算目标路径, 第一个可能是单文件
-		if dst == "" && strings.HasSuffix(source, ".go") {
-			source = filepath.Dir(source)
+// importMode 以 target 下 format 格式文件中的译文更新 source 下对应的翻译文档,
+// 更新记录输出到 Stdout. 未指定 lang 时更新结果输出到 Stdout, 记录输出到 Stderr.
+func importMode(ch chan interface{}, target, lib, lang string) error {
+	var out bool
+	stdout := lang == ""
+	report := os.Stdout
+	if stdout {
+		report = os.Stderr
+	}
+	ext := docu.FormatExt[format]
+	return parallel(ch, func(source string) (func() error, error) {
+		lang := lookLang(lang, dirOf(source), lib)
+		if lang == "" {
+			return nil, nil
+		}
+		du := docu.New()
+		du.Filter = genNameFilter(lib, lang)
+		paths, err := du.Parse(source, nil)
+		if err != nil || len(paths) == 0 {
+			return nil, err
 		}
-		dst = filepath.Join(target, source[offset:])
 
-		paths, err = tu.Parse(filepath.Join(dst, fname), nil)
+		key := paths
+		fname := genFileName(lib, lang, ".go")
+		r, err := os.Open(filepath.Join(targetDir(target, source, key),
+			genFileName(lib, lang, ext)))
 		if os.IsNotExist(err) {
-			err = nil
+			return nil, nil
 		}
 		if err != nil {
-			break
+			return nil, err
 		}
-		dis := tu.MergePackageFiles(key)
-		if dis == nil || paths != key {
-			ch <- nil
-			continue
+		units, err := docu.ReadUnits(r, format)
+		r.Close()
+		if err != nil {
+			return nil, errors.New(key + ": " + err.Error())
 		}
 
-		src := du.MergePackageFiles(key)
-		if !docu.IsGodocuFile(src) || !docu.IsGodocuFile(dis) {
-			err = errors.New("source and target must be GodocuStyle documents")
-			break
+		file := du.MergePackageFiles(key)
+		n := docu.ImportUnits(key, file, units)
+		if n == 0 {
+			return nil, nil
+		}
+		var buf bytes.Buffer
+		if err = docu.Fprint(&buf, file); err != nil {
+			return nil, err
+		}
+		dst := dirOf(source)
+		if stdout {
+			dst = ""
+		}
+		return func() error {
+			fmt.Fprintf(report, "%s: imported %d of %d units\n", key, n, len(units))
+			return writeOutput(dst, fname, &out, buf.Bytes())
+		}, nil
+	})
+}
+
+// lookGlossary 在 dir 及其上级目录中查找 lang 的术语表文件 "glossary_<lang>.txt".
+// 未找到时返回 dir 下的文件名.
+func lookGlossary(dir, lang string) string {
+	name := "glossary_" + lang + ".txt"
+	for path := dir; ; {
+		if _, err := os.Stat(filepath.Join(path, name)); err == nil {
+			return filepath.Join(path, name)
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return filepath.Join(dir, name)
 		}
+		path = parent
+	}
+}
 
-		// 自动提取第一个 lang, 只是为了过滤
+// glossaryMode 以术语表 g 检查 source 下的翻译文档, 输出译文不符合术语表的声明.
+func glossaryMode(g docu.Glossary, ch chan interface{}, lib, lang string) error {
+	return parallel(ch, func(source string) (func() error, error) {
+		lang := lookLang(lang, dirOf(source), lib)
 		if lang == "" {
-			lang = du.NormalLang(key)
-			if lang == "" || lang != tu.NormalLang(key) {
-				err = errors.New("missing argument lang")
-				break
+			return nil, nil
+		}
+		du := docu.New()
+		du.Filter = genNameFilter(lib, lang)
+		paths, err := du.Parse(source, nil)
+		if err != nil || len(paths) == 0 {
+			return nil, err
+		}
+
+		key := paths
+		records := docu.CheckGlossary(du.MergePackageFiles(key), g)
+		if len(records) == 0 {
+			return nil, nil
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		for _, r := range records {
+			r.Package = key
+			if jsonOut {
+				err = enc.Encode(r)
+			} else if r.Kind == docu.TermMissing {
+				_, err = fmt.Fprintf(&buf, "%s %s: missing %q for %q\n",
+					key, r.Ident, r.Target, r.Term)
+			} else {
+				_, err = fmt.Fprintf(&buf, "%s %s: forbidden %q for %q, use %q\n",
+					key, r.Ident, r.Variant, r.Term, r.Target)
+			}
+			if err != nil {
+				return nil, err
 			}
-			du.Filter = genNameFilter(lib, lang)
-			tu.Filter = du.Filter
-			fname = genFileName(lib, lang, ".go")
-			lang = "."
 		}
+		return func() error {
+			_, err := os.Stdout.Write(buf.Bytes())
+			return err
+		}, nil
+	})
+}
 
-		docu.Replace(dis, src)
+// lintMode 检查 source 包在 target 下对应目录中的文件命名和翻译文档, 输出发现的问题.
+func lintMode(ch chan interface{}, target, lib, lang string) error {
+	du := docu.New()
+	du.Filter = genNameFilter(lib, "")
 
-		if len(dis.Imports) == 0 {
-			dis.Imports = src.Imports
+	return parallel(ch, func(source string) (func() error, error) {
+		paths, err := du.Parse(source, nil)
+		if docu.IsMultiplePkgError(err) {
+			return nil, nil
 		}
-
-		if lang != "." {
-			output, err = createFile(dst, fname)
+		if err != nil || len(paths) == 0 {
+			return nil, err
 		}
 
-		if err != nil {
-			break
+		key := paths
+		dst := targetDir(target, source, key)
+		records := docu.LintDir(dst)
+		if lang := lookLang(lang, dst, lib); lang != "" {
+			rs, err := docu.LintFile(filepath.Join(dst, genFileName(lib, lang, ".go")),
+				nil, du.MergePackageFiles(key))
+			if err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			records = append(records, rs...)
+		}
+		if len(records) == 0 {
+			return nil, nil
 		}
 
-		if lang == "." {
-			if out {
-				_, err = os.Stdout.WriteString(sp)
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		for _, r := range records {
+			if jsonOut {
+				err = enc.Encode(r)
+			} else {
+				_, err = fmt.Fprintln(&buf, r)
+			}
+			if err != nil {
+				return nil, err
 			}
-			out = true
 		}
+		return func() error {
+			_, err := os.Stdout.Write(buf.Bytes())
+			return err
+		}, nil
+	})
+}
 
-		if err == nil {
-			err = docu.Fprint(output, dis)
+func replaceMode(ch chan interface{},
+	target, lib, lang string) error {
+
+	var out bool
+	// 未指定 lang 时输出到 os.Stdout
+	stdout := lang == ""
+	du := docu.New()
+	du.Filter = genNameFilter(lib, lang)
+
+	// 以 target 限制为过滤条件, 因此允许所有
+	return parallel(ch, func(source string) (func() error, error) {
+		lang := lookLang(lang, dirOf(source), lib)
+		su := du
+		if stdout {
+			su = docu.New()
+			su.Filter = genNameFilter(lib, lang)
 		}
 
-		if output != os.Stdout {
-			output.Close()
+		paths, err := su.Parse(source, nil)
+		if err != nil || len(paths) == 0 {
+			return nil, err
 		}
 
+		key := paths
+		dst := targetDir(target, source, key)
+		fname := genFileName(lib, lang, ".go")
+
+		tu := docu.New()
+		tu.Filter = su.Filter
+		paths, err = tu.Parse(filepath.Join(dst, fname), nil)
+		if os.IsNotExist(err) {
+			err = nil
+		}
 		if err != nil {
-			break
+			return nil, err
 		}
-		ch <- nil
-	}
-	return
+		dis := tu.MergePackageFiles(key)
+		if dis == nil || paths != key {
+			return nil, nil
+		}
+		if lang == "" {
+			return nil, errors.New("missing argument lang")
+		}
+
+		src := su.MergePackageFiles(key)
+		if !docu.IsGodocuFile(src) || !docu.IsGodocuFile(dis) {
+			return nil, errors.New("source and target must be GodocuStyle documents")
+		}
+
+		docu.Replace(dis, src)
+
+		if len(dis.Imports) == 0 {
+			dis.Imports = src.Imports
+		}
+
+		var buf bytes.Buffer
+		if err = docu.Fprint(&buf, dis); err != nil {
+			return nil, err
+		}
+		if stdout {
+			dst = ""
+		}
+		return func() error {
+			return writeOutput(dst, fname, &out, buf.Bytes())
+		}, nil
+	})
 }
 
-func listMode(ch chan interface{}, offset int, target, lib, lang string) (err error) {
-	var ok bool
-	var source string
-	var paths string
+func listMode(ch chan interface{}, target, lib, lang string) (err error) {
 	var list docu.List
 	var bs []byte
 
+	// 未指定 lang 时输出到 os.Stdout
+	stdout := target == "" || lang == ""
 	du := docu.New()
 	du.Filter = genNameFilter(lib, lang)
 	list.Filename = genFileName(lib, lang, ".go")
@@ -974,87 +1482,66 @@ func listMode(ch chan interface{}, offset int, target, lib, lang string) (err er
 			lang = docu.LangOf(list.Filename)
 			list.Filename = genFileName(lib, lang, ".go")
 			du.Filter = genNameFilter(lib, lang)
-			lang = "."
 		}
 	}
 
-	for i := <-ch; i != nil; i = <-ch {
-		if err, ok = i.(error); !ok {
-			source = i.(string)
-			paths, err = du.Parse(source, nil)
+	// 仍未确定 lang 时逐包推断, 只是为了过滤
+	auto := lang == ""
+	err = parallel(ch, func(source string) (func() error, error) {
+		lang := lookLang(lang, source, lib)
+		pu := du
+		if auto {
+			pu = docu.New()
+			pu.Filter = genNameFilter(lib, lang)
 		}
+
+		paths, err := pu.Parse(source, nil)
 		if err != nil {
-			break
+			return nil, err
 		}
 
 		if len(paths) == 0 {
 			// 简单预测官方包
-			if list.Repo == "" {
-				if filepath.Base(source) == "archive" {
+			if filepath.Base(source) != "archive" {
+				return nil, nil
+			}
+			return func() error {
+				if list.Repo == "" {
 					list.Repo = "github.com/golang/go"
 				}
-			}
-			ch <- nil
-			continue
-		}
-		key := paths
-		// 自动提取第一个 lang, 只是为了过滤
-		if lang == "" {
-			lang = du.NormalLang(key)
-			list.Filename = genFileName(lib, lang, ".go")
-			du.Filter = genNameFilter(lib, lang)
-			lang = "."
+				return nil
+			}, nil
 		}
 
-		file := du.MergePackageFiles(key)
-
+		key := paths
+		file := pu.MergePackageFiles(key)
 		info := docu.Info{
-			Synopsis: doc.Synopsis(file.Doc.Text()),
-			Progress: docu.TranslationProgress(file),
-			Readme:   docu.LookReadme(source),
-			Import:   source[offset:],
-		}
-
-		list.Package = append(list.Package, info)
-
-		if list.Repo == "" {
-			// 官方包引向 github, 其它引向 "localhost"
-			imp := info.Import
-			if strings.HasPrefix(imp, "golang.org/x") {
-				list.Repo = "github.com/golang/tools"
-			} else if pos := strings.IndexByte(imp, '/'); pos != -1 {
-				imp = imp[:pos]
-			}
-			if list.Repo == "" && strings.IndexByte(imp, '.') != -1 {
-				for _, wh := range docu.Warehouse {
-					if wh.Host != imp {
-						continue
-					}
-					pos := 1
-					for i := 0; i < wh.Part; i++ {
-						end := strings.IndexByte(info.Import[pos:], '/')
-						if end == -1 {
-							break
-						}
-						pos += end + 1
-					}
-					list.Repo = info.Import[:pos-1]
-					break
-				}
+			Synopsis:      doc.Synopsis(file.Doc.Text()),
+			GroupProgress: docu.TranslationProgress(file),
+			Progress:      docu.ParagraphProgress(file),
+			Readme:        docu.LookReadme(source),
+			Import:        key,
+			Stale:         docu.StaleCount(file),
+			Deprecated:    docu.DeprecatedCount(file),
+		}
+
+		return func() error {
+			if auto && list.Filename == "" {
+				list.Filename = genFileName(lib, lang, ".go")
 			}
+			list.Package = append(list.Package, info)
 			if list.Repo == "" {
-				list.Repo = "localhost"
+				list.Repo = repoOf(info.Import)
 			}
-		}
-
-		ch <- nil
-	}
+			return nil
+		}, nil
+	})
 
 	if err == nil {
 		bs, err = json.MarshalIndent(list, "", "    ")
 	}
 	if err == nil {
-		if target == "" || lang == "." {
+		if stdout {
 			_, err = os.Stdout.Write(bs)
 			return
 		}
@@ -1067,3 +1554,32 @@ func listMode(ch chan interface{}, offset int, target, lib, lang string) (err er
 	}
 	return
 }
+
+// repoOf 返回 import paths 为 imp 的包所在的仓库.
+// 官方包引向 github, 其它引向 "localhost".
+func repoOf(imp string) string {
+	if strings.HasPrefix(imp, "golang.org/x") {
+		return "github.com/golang/tools"
+	}
+	host := imp
+	if pos := strings.IndexByte(host, '/'); pos != -1 {
+		host = host[:pos]
+	}
+	if strings.IndexByte(host, '.') != -1 {
+		for _, wh := range docu.Warehouse {
+			if wh.Host != host {
+				continue
+			}
+			pos := 1
+			for i := 0; i < wh.Part; i++ {
+				end := strings.IndexByte(imp[pos:], '/')
+				if end == -1 {
+					break
+				}
+				pos += end + 1
+			}
+			return imp[:pos-1]
+		}
+	}
+	return "localhost"
+}
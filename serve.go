@@ -0,0 +1,240 @@
+//go:build go1.5
+// +build go1.5
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"html/template"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang-china/godocu/docu"
+)
+
+// 文档视图
+const (
+	viewOrigin      = "origin"      // 原文
+	viewTranslation = "translation" // 译文
+	viewBilingual   = "bilingual"   // 双语
+)
+
+var views = []string{viewTranslation, viewBilingual, viewOrigin}
+
+// httpAddr 为 serve 指令的监听地址.
+var httpAddr string
+
+// server 按需渲染 root 或 mod 下的源码包和 target 下对应的翻译文档.
+type server struct {
+	root, target, lib, lang string
+	mod                     *docu.Module
+	u                       bool
+}
+
+// serveMode 在 httpAddr 启动 HTTP 服务, 页面和样式均内置, 无需网络.
+// mod 非 nil 时包目录由 mod.PathDir 计算, 否则 root 为 GOPATH 风格的源码树根目录,
+// 包目录为 root 下的 import paths.
+func serveMode(root string, mod *docu.Module, target, lib, lang string, u bool) error {
+	s := &server{root: root, mod: mod, target: target, lib: lib, lang: lang, u: u}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.index)
+	mux.HandleFunc("/pkg/", s.pkg)
+	log.Println("serving on http://" + httpAddr)
+	return http.ListenAndServe(httpAddr, mux)
+}
+
+// index 输出 target 下 golist.json 中的包清单.
+func (s *server) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	var list docu.List
+	bs, err := ioutil.ReadFile(filepath.Join(s.target, "golist.json"))
+	if err == nil {
+		err = json.Unmarshal(bs, &list)
+	}
+	if err != nil {
+		http.Error(w, err.Error()+"\nrun godocu list to generate golist.json",
+			http.StatusInternalServerError)
+		return
+	}
+	s.execute(w, indexTemplate, list)
+}
+
+// pageData 为包页面的模板数据.
+type pageData struct {
+	Import     string
+	View       string
+	Views      []string
+	Translated bool
+	Progress   int
+	Code       template.HTML
+}
+
+// pkg 输出 "/pkg/" 之后 import paths 所指包的文档, 参数 view 选择视图.
+func (s *server) pkg(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(path.Clean(r.URL.Path), "/pkg/")
+	if key == "" || key == "/pkg" {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	view := r.FormValue("view")
+	if view != viewOrigin && view != viewBilingual {
+		view = viewTranslation
+	}
+	data, err := s.render(key, view)
+	if os.IsNotExist(err) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.execute(w, pageTemplate, data)
+}
+
+func (s *server) execute(w http.ResponseWriter, tpl *template.Template, data interface{}) {
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// dir 返回 import paths 为 key 的包目录, 不属于 s.mod 时返回 "".
+func (s *server) dir(key string) string {
+	if s.mod != nil {
+		return s.mod.PathDir(key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key))
+}
+
+// render 解析合并 key 对应的源码包和翻译文档, 以 docu.Fprint 生成 view 视图.
+// 没有翻译文档时总是输出原文.
+func (s *server) render(key, view string) (*pageData, error) {
+	source := s.dir(key)
+	if source == "" {
+		return nil, os.ErrNotExist
+	}
+	du := docu.New()
+	du.Filter = genNameFilter(s.lib, "")
+	paths, err := du.Parse(source, nil)
+	if err != nil {
+		return nil, err
+	}
+	if paths != key {
+		return nil, os.ErrNotExist
+	}
+	src := du.MergePackageFiles(key)
+
+	var dis *ast.File
+	dst := targetDir(s.target, source, key)
+	if lang := lookLang(s.lang, dst, s.lib); lang != "" {
+		tu := docu.New()
+		tu.Filter = genNameFilter(s.lib, lang)
+		paths, err = tu.Parse(filepath.Join(dst, genFileName(s.lib, lang, ".go")), nil)
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if paths == key {
+			dis = tu.MergePackageFiles(key)
+		}
+	}
+
+	data := &pageData{Import: key, View: view, Views: views, Translated: dis != nil}
+	if !s.u {
+		if dis != nil {
+			// 以目标过滤源
+			docu.SortDecl(dis.Decls).Filter(src)
+		} else {
+			docu.ExportedFileFilter(src)
+		}
+	}
+
+	file := src
+	if dis != nil {
+		data.Progress = docu.ParagraphProgress(dis)
+		switch view {
+		case viewTranslation:
+			// 不再输出原文档
+			dis.Unresolved = nil
+			file = dis
+		case viewBilingual:
+			if !docu.EqualComment(src.Doc, dis.Doc) {
+				docu.MergeDoc(dis.Doc, src.Doc)
+			}
+			docu.MergeDeclsDoc(dis.Decls, src.Decls)
+			docu.MergeNotes(dis, src)
+		}
+	}
+	file.Unresolved = nil
+
+	var buf bytes.Buffer
+	docu.Orders[order](file)
+	if err = docu.Fprint(&buf, file); err != nil {
+		return nil, err
+	}
+	data.Code = template.HTML(docu.HTMLCode(buf.String(), nil))
+	return data, nil
+}
+
+const serveStyle = `<style>
+body { font-family: sans-serif; margin: 0 auto; max-width: 960px; padding: 0 20px; line-height: 1.5; }
+pre { background: #f5f5f5; padding: 10px; overflow-x: auto; line-height: 1.4; }
+a { color: #375eab; text-decoration: none; }
+td { padding: 2px 10px 2px 0; vertical-align: top; }
+.kw { color: #00f; }
+.com { color: #080; }
+.str { color: #a31515; }
+.num { color: #098658; }
+</style>`
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Repo}} - Godocu</title>
+` + serveStyle + `
+</head>
+<body>
+<h1>{{.Repo}}</h1>
+{{if .Description}}<p>{{.Description}}</p>
+{{end}}<table>
+<tr><th align="left">Package</th><th align="left">Synopsis</th><th align="right">Progress</th></tr>
+{{range .Package}}<tr><td><a href="/pkg/{{.Import}}">{{.Import}}</a></td><td>{{.Synopsis}}</td><td align="right">{{.Progress}}%</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Import}} - Godocu</title>
+` + serveStyle + `
+</head>
+<body>
+<p><a href="/">Packages</a> / {{.Import}}</p>
+<h1>{{.Import}}</h1>
+{{if .Translated}}<p>{{range .Views}}{{if eq . $.View}}<b>{{.}}</b>{{else}}<a href="?view={{.}}">{{.}}</a>{{end}} {{end}}</p>
+<p>Translation Progress: {{.Progress}}%</p>
+{{else}}<p>No translation</p>
+{{end}}<pre>{{.Code}}</pre>
+</body>
+</html>
+`))
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang-china/godocu/docu"
+)
+
+func TestServeModule(t *testing.T) {
+	dir, err := ioutil.TempDir("", "godocu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"go.mod":     "module example.com/proj\n",
+		"sub/sub.go": "// Package sub is sub.\npackage sub\n\n// F does f.\nfunc F() {}\n",
+	}
+	for name, src := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(name, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		t.Fatal(err)
+	}
+	order = "index"
+	mod := docu.LookModule(dir)
+	if mod == nil || mod.Path != "example.com/proj" {
+		t.Fatalf("LookModule = %+v", mod)
+	}
+	s := &server{root: dir, mod: mod, target: filepath.Join(dir, "target"), lib: "package"}
+
+	for _, tt := range []struct {
+		path string
+		code int
+	}{
+		{"/pkg/example.com/proj/sub", http.StatusOK},
+		{"/pkg/example.com/proj/none", http.StatusNotFound},
+		{"/pkg/example.com/other/sub", http.StatusNotFound},
+	} {
+		w := httptest.NewRecorder()
+		s.pkg(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.code {
+			t.Errorf("GET %s = %d, want %d\n%s", tt.path, w.Code, tt.code, w.Body.String())
+			continue
+		}
+		if tt.code == http.StatusOK && !strings.Contains(w.Body.String(), "F does f.") {
+			t.Errorf("GET %s =\n%s", tt.path, w.Body.String())
+		}
+	}
+}